@@ -1,7 +1,7 @@
 package cwl
 
 import (
-	"strings"
+	"reflect"
 )
 
 func (t *Tool) RequiresDocker() (*DockerRequirement, bool) {
@@ -48,93 +48,89 @@ func (t *Tool) RequiresSchemaDef() (*SchemaDefRequirement, bool) {
 	return nil, false
 }
 
-func (t *Tool) ResolveSchemaDefs() error {
-	defs, required := t.RequiresSchemaDef()
-	if !required {
-		return nil
-	}
-
-	byName := map[string]SchemaDef{}
-	for _, def := range defs.Types {
-		byName[def.Name] = def
+func (t *Tool) RequiresLoadListing() (*LoadListingRequirement, bool) {
+	reqs := append([]Requirement{}, t.Requirements...)
+	reqs = append(reqs, t.Hints...)
+	for _, req := range reqs {
+		if r, ok := req.(LoadListingRequirement); ok {
+			return &r, true
+		}
 	}
+	return nil, false
+}
 
-	for _, in := range t.Inputs {
-		for i, r := range in.Type {
-			t, err := resolveSchemaDef(byName, r)
-			if err != nil {
-				return err
+// MergeRequirements merges requirements declared at different levels of a workflow,
+// given in order from the most to the least specific, e.g. tool, step, workflow.
+// For each requirement class, only the most specific requirement is kept.
+//
+// cwl spec:
+// "If the same process requirement appears at different levels of the workflow,
+// the most specific instance of the requirement is used, that is, an entry in
+// requirements on a process implementation such as CommandLineTool will take
+// precedence over an entry in requirements specified in a workflow step,
+// and an entry in requirements on a workflow step takes precedence over the workflow."
+func MergeRequirements(levels ...[]Requirement) []Requirement {
+	var out []Requirement
+	seen := map[string]bool{}
+	for _, reqs := range levels {
+		for _, req := range reqs {
+			class := requirementClass(req)
+			if seen[class] {
+				continue
 			}
-			inputType, ok := t.(InputType)
-			if !ok {
-				return errf("input type schema reference resolved to non-input type")
-			}
-			in.Type[i] = inputType
+			seen[class] = true
+			out = append(out, req)
 		}
 	}
-	return nil
+	return out
 }
 
-// resolveSchemaDef recursively resolves references to SchemaDefRequirement types
-// by name. Note that schemas defined in the requirement may themselves refer
-// to schemas by name. If no type mapping is done, resolveSchemaDef returns the
-// original type.
-func resolveSchemaDef(byName map[string]SchemaDef, in interface{}) (interface{}, error) {
-	switch z := in.(type) {
-
-	case TypeRef:
-		name := strings.TrimPrefix(z.Name, "#")
-		def, found := byName[name]
-		if !found {
-			return nil, errf(`no schema def named "%s"`, z.Name)
-		}
-		// A schema type defined in a SchemaDefRequirement may itself refer
-		// to other types by name, so recursively resolve this type.
-		//
-		// TODO there is a chance of infinite recursion here if types
-		//      have a circular reference. Would be nice to catch this with
-		//      a good error message.
-		t, err := resolveSchemaDef(byName, def.Type.(cwltype))
-		if err != nil {
-			return nil, err
-		}
-		return t, nil
-
-	case InputField:
-		for i, f := range z.Type {
-			t, err := resolveSchemaDef(byName, f)
-			if err != nil {
-				return nil, err
-			}
-			inputType, ok := t.(InputType)
-			if !ok {
-				return nil, errf("input type schema reference resolved to non-input type")
-			}
-			z.Type[i] = inputType
+// MergeHints merges hints like MergeRequirements, and additionally drops
+// any hint with the same class as one of the given requirements.
+//
+// cwl spec:
+// "Requirements override hints. If a process implementation provides a process
+// requirement in hints which is also provided in requirements by an enclosing
+// workflow or workflow step, the enclosing requirements takes precedence."
+func MergeHints(reqs []Requirement, levels ...[]Requirement) []Requirement {
+	var out []Requirement
+	seen := map[string]bool{}
+	for _, req := range reqs {
+		seen[requirementClass(req)] = true
+	}
+	for _, hint := range MergeRequirements(levels...) {
+		if !seen[requirementClass(hint)] {
+			out = append(out, hint)
 		}
+	}
+	return out
+}
 
-	case InputRecord:
-		for _, f := range z.Fields {
-			_, err := resolveSchemaDef(byName, f)
-			if err != nil {
-				return nil, err
-			}
-		}
+// ResolveRequirements computes the requirements and hints in effect for the
+// document run by the step, given the requirements and hints in effect for
+// the workflow containing the step.
+func (s *Step) ResolveRequirements(wfReqs, wfHints []Requirement) (reqs, hints []Requirement) {
+	var runReqs, runHints []Requirement
+	switch z := s.Run.(type) {
+	case *Tool:
+		runReqs, runHints = z.Requirements, z.Hints
+	case *ExpressionTool:
+		runReqs, runHints = z.Requirements, z.Hints
+	case *Workflow:
+		runReqs, runHints = z.Requirements, z.Hints
+	}
+	reqs = MergeRequirements(runReqs, s.Requirements, wfReqs)
+	hints = MergeHints(reqs, runHints, s.Hints, wfHints)
+	return reqs, hints
+}
 
-	case InputArray:
-		for i, f := range z.Items {
-			t, err := resolveSchemaDef(byName, f)
-			if err != nil {
-				return nil, err
-			}
-			inputType, ok := t.(InputType)
-			if !ok {
-				return nil, errf("input type schema reference resolved to non-input type")
-			}
-			z.Items[i] = inputType
-		}
+// requirementClass returns the class name of a requirement,
+// e.g. "DockerRequirement".
+func requirementClass(r Requirement) string {
+	if u, ok := r.(UnknownRequirement); ok {
+		return u.Name
 	}
-	return in, nil
+	return reflect.TypeOf(r).Name()
 }
 
 func (clb *CommandLineBinding) GetLoadContents() bool {
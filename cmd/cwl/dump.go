@@ -47,11 +47,14 @@ func dump(opts dumpOpts, path string) error {
 
   // TODO also resolve http/file references for schema types?
   if opts.resolveSchemaDefs {
-    if tool, ok := doc.(*cwl.Tool); ok {
-      err := tool.ResolveSchemaDefs()
-      if err != nil {
-        return err
-      }
+    switch z := doc.(type) {
+    case *cwl.Tool:
+      err = z.ResolveSchemaDefs()
+    case *cwl.Workflow:
+      err = z.ResolveSchemaDefs()
+    }
+    if err != nil {
+      return err
     }
   }
 
@@ -0,0 +1,43 @@
+package main
+
+import (
+  "fmt"
+  "github.com/lijiang2014/cwl"
+  "github.com/spf13/cobra"
+)
+
+type packOpts struct {
+  yaml bool
+}
+
+func init() {
+  opts := packOpts{}
+
+  cmd := &cobra.Command{
+    Use: "pack <doc.cwl>",
+    Short: "Pack a document and the documents it references into a single $graph document",
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+      return pack(opts, args[0])
+    },
+  }
+  root.AddCommand(cmd)
+
+  f := cmd.Flags()
+  f.BoolVar(&opts.yaml, "yaml", opts.yaml, "")
+}
+
+func pack(opts packOpts, path string) error {
+  g, err := cwl.PackFile(path, cwl.DefaultResolver{})
+  if err != nil {
+    return err
+  }
+
+  b, err := marshalDoc(g, !opts.yaml)
+  if err != nil {
+    return err
+  }
+
+  fmt.Println(string(b))
+  return nil
+}
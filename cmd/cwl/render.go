@@ -0,0 +1,64 @@
+package main
+
+import (
+  "fmt"
+  "os"
+  "path/filepath"
+
+  "github.com/lijiang2014/cwl"
+  "github.com/lijiang2014/cwl/process"
+  localfs "github.com/lijiang2014/cwl/process/fs/local"
+  "github.com/lijiang2014/cwl/process/k8s"
+  "github.com/spf13/cobra"
+)
+
+func init() {
+  render := &cobra.Command{
+    Use: "render",
+    Short: "Render the job of a tool for a platform, without running it",
+  }
+  root.AddCommand(render)
+
+  r := k8s.Renderer{}
+  cmd := &cobra.Command{
+    Use: "k8s <tool.cwl> <inputs.json>",
+    Short: "Render Kubernetes manifests running a tool",
+    Args: cobra.ExactArgs(2),
+    RunE: func(cmd *cobra.Command, args []string) error {
+      return renderK8s(&r, args[0], args[1])
+    },
+  }
+  render.AddCommand(cmd)
+
+  f := cmd.Flags()
+  f.StringVar(&r.Namespace, "namespace", r.Namespace, "namespace of the rendered objects")
+  f.StringVar(&r.DefaultImage, "default-image", r.DefaultImage, "image of tools which don't require a docker image")
+}
+
+func renderK8s(r *k8s.Renderer, path, inputsPath string) error {
+  vals, err := cwl.LoadValuesFile(inputsPath)
+  if err != nil {
+    return err
+  }
+
+  doc, err := cwl.Load(path)
+  if err != nil {
+    return err
+  }
+  tool, ok := doc.(*cwl.Tool)
+  if !ok {
+    return fmt.Errorf(`rendering doc: expected a CommandLineTool, got "%s"`, doc.Doctype())
+  }
+
+  fs := localfs.NewLocal(filepath.Dir(inputsPath))
+  rt := process.Runtime{Outdir: "/cwl", Tmpdir: "/tmp"}
+  proc, err := process.NewProcess(tool, vals, rt, fs)
+  if err != nil {
+    return err
+  }
+  job, err := process.NewJob(proc)
+  if err != nil {
+    return err
+  }
+  return r.Render(os.Stdout, job)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+  "github.com/lijiang2014/cwl/process"
   "github.com/spf13/cobra"
   "os"
 )
@@ -12,6 +13,15 @@ var root = cobra.Command{
 
 func main() {
   if err := root.Execute(); err != nil {
-    os.Exit(1)
+    os.Exit(exitCode(process.StatusOf(err)))
   }
 }
+
+// exitCode returns the exit code of the cwl command for a failed run:
+// 75 (EX_TEMPFAIL from sysexits.h) for a temporaryFail, otherwise 1.
+func exitCode(status process.Status) int {
+  if status == process.TemporaryFail {
+    return 75
+  }
+  return 1
+}
@@ -10,6 +10,8 @@ import (
   "github.com/lijiang2014/cwl"
   "github.com/lijiang2014/cwl/process"
   localfs "github.com/lijiang2014/cwl/process/fs/local"
+  "io/ioutil"
+  "os"
   "path/filepath"
   //gsfs "github.com/lijiang2014/cwl/process/fs/gs"
   
@@ -17,20 +19,52 @@ import (
   "github.com/lijiang2014/tugboat/storage/local"
   //gsstore "github.com/buchanae/tugboat/storage/gs"
   
-  "github.com/rs/xid"
+  dockerexec "github.com/lijiang2014/cwl/process/exec/docker"
+  "github.com/lijiang2014/cwl/process/exec/dryrun"
+  localexec "github.com/lijiang2014/cwl/process/exec/local"
+  "github.com/lijiang2014/cwl/process/exec/slurm"
   "github.com/spf13/cobra"
 )
 
 func init() {
   outdir := "cwl-output"
   debug := false
+  statePath := ""
+  resumePath := ""
+  cacheDir := ""
+  retries := 0
+  executor := "tugboat"
+  defaultImage := ""
 
   cmd := &cobra.Command{
     Use: "run <doc.cwl> <inputs.json>",
     Short: "run",
     Args: cobra.ExactArgs(2),
     RunE: func(cmd *cobra.Command, args []string) error {
-      return run(args[0], args[1], outdir, debug)
+      if statePath != "" && resumePath != "" {
+        return fmt.Errorf("--state and --resume are mutually exclusive")
+      }
+
+      var state *process.State
+      if resumePath != "" {
+        s, err := process.LoadState(resumePath)
+        if err != nil {
+          return fmt.Errorf("loading workflow state: %s", err)
+        }
+        state = s
+      } else if statePath != "" {
+        state = process.NewState(statePath)
+      }
+
+      var cache process.Cache
+      if cacheDir != "" {
+        c, err := process.NewDirCache(cacheDir)
+        if err != nil {
+          return err
+        }
+        cache = c
+      }
+      return run(args[0], args[1], outdir, debug, state, cache, retries, executor, defaultImage)
     },
   }
   root.AddCommand(cmd)
@@ -38,9 +72,15 @@ func init() {
 
   f.StringVar(&outdir, "outdir", outdir, "")
   f.BoolVar(&debug, "debug", debug, "")
+  f.StringVar(&statePath, "state", statePath, "save the workflow state to this file, so the run can be resumed")
+  f.StringVar(&resumePath, "resume", resumePath, "resume the workflow run saved in this state file")
+  f.StringVar(&cacheDir, "cache-dir", cacheDir, "reuse the outputs of identical jobs cached in this directory")
+  f.IntVar(&retries, "retries", retries, "run a job again up to this many times when it fails with a temporaryFail exit code")
+  f.StringVar(&executor, "executor", executor, "run jobs with this executor: tugboat, local, docker, slurm or dry-run")
+  f.StringVar(&defaultImage, "default-image", defaultImage, "with --executor docker, image of tools which don't require a docker image")
 }
 
-func run(path, inputsPath, outdir string, debug bool) error {
+func run(path, inputsPath, outdir string, debug bool, state *process.State, cache process.Cache, retries int, executor, defaultImage string) error {
   fmt.Println("local cwl run.")
   vals, err := cwl.LoadValuesFile(inputsPath)
   if err != nil {
@@ -53,9 +93,10 @@ func run(path, inputsPath, outdir string, debug bool) error {
     return err
   }
 
-  r := runner{inputsDir, outdir, debug}
+  r := runner{inputsDir: inputsDir, outdir: outdir, debug: debug, state: state, cache: cache, retries: retries, executor: executor, defaultImage: defaultImage}
 
   outvals, err := r.runDoc(doc, vals)
+  fmt.Fprintf(os.Stderr, "Final process status is %s\n", process.StatusOf(err))
   if err != nil {
     return err
   }
@@ -73,115 +114,169 @@ type runner struct {
   inputsDir string
   outdir string
   debug bool
+  // jobDirs places the outputs of each job in a separate directory
+  // under outdir, so that the outputs of workflow steps don't collide.
+  jobDirs bool
+  // state records completed workflow jobs, if non-nil.
+  state *process.State
+  // cache stores the outputs of tool jobs, if non-nil.
+  cache process.Cache
+  // retries is the number of times a job is run again after a temporaryFail.
+  retries int
+  // executor names the executor running jobs. The default, "tugboat",
+  // is the runner itself.
+  executor string
+  // defaultImage is the image of tools which don't require a docker image,
+  // used by the docker executor.
+  defaultImage string
 }
 
 func (r *runner) runDoc(doc cwl.Document, vals cwl.Values) (cwl.Values, error) {
+  fs := localfs.NewLocal(r.inputsDir)
+  fs.CalcChecksum = true
+  //fs, err := gsfs.NewGS("buchanae-funnel")
+  //if err != nil {
+    //return nil, err
+  //}
+
+  engine := &process.Engine{
+    Runtime: process.Runtime{
+      Outdir: "/cwl",
+    },
+    Filesystem: fs,
+    Executor: r,
+    State: r.state,
+    Cache: r.cache,
+    Retries: r.retries,
+  }
+
+  switch r.executor {
+  case "", "tugboat":
+  case "local":
+    // Jobs run on the host, in their own directories under outdir.
+    dir, err := filepath.Abs(r.outdir)
+    if err != nil {
+      return nil, err
+    }
+    engine.JobDirs = dir
+    engine.Executor = process.NewSessionExecutor(&localexec.Local{CalcChecksum: true}, sessionLog{})
+  case "docker":
+    dir, err := filepath.Abs(r.outdir)
+    if err != nil {
+      return nil, err
+    }
+    engine.Executor = process.NewSessionExecutor(&dockerexec.Docker{
+      Dir: dir,
+      DefaultImage: r.defaultImage,
+      CalcChecksum: true,
+    }, sessionLog{})
+  case "slurm":
+    // Jobs are submitted with sbatch, so outdir must be shared with the compute nodes.
+    dir, err := filepath.Abs(r.outdir)
+    if err != nil {
+      return nil, err
+    }
+    engine.JobDirs = dir
+    engine.Executor = process.NewSessionExecutor(&slurm.Slurm{
+      Dir: filepath.Join(dir, ".slurm"),
+      CalcChecksum: true,
+    }, sessionLog{})
+  case "dry-run":
+    engine.Executor = process.NewToolExecutor(&dryrun.DryRun{Out: os.Stderr})
+  default:
+    return nil, fmt.Errorf("unknown executor %q", r.executor)
+  }
+
   switch z := doc.(type) {
   case *cwl.Tool:
-    return r.runTool(z, vals)
+    return engine.RunTool(z, vals)
+  case *cwl.ExpressionTool:
+    return engine.RunExpressionTool(z, vals)
   case *cwl.Workflow:
-    return r.runWorkflow(z, vals)
+    r.jobDirs = true
+    return engine.RunWorkflow(z, vals)
+  case cwl.Graph:
+    // A packed document runs its "#main" entry,
+    // unless the path selected another, e.g. "packed.cwl#other".
+    entry, err := z.Entry("")
+    if err != nil {
+      return nil, err
+    }
+    return r.runDoc(entry, vals)
   default:
     return nil, fmt.Errorf(`running doc: unknown doc type "%s"`, doc.Doctype())
   }
 }
 
-func (r *runner) runWorkflow(wf *cwl.Workflow, vals cwl.Values) (cwl.Values, error) {
-  process.DebugWorkflow(wf, vals)
-  return nil, nil
-}
-
-func (r *runner) runTool(tool *cwl.Tool, vals cwl.Values) (cwl.Values, error) {
-  // TODO hack. need to think carefully about how resource requirement and runtime
-  //      actually get scheduled.
-  var resources *cwl.ResourceRequirement
-	reqs := append([]cwl.Requirement{}, tool.Requirements...)
-	reqs = append(reqs, tool.Hints...)
-  for _, req := range reqs {
-    if r, ok := req.(cwl.ResourceRequirement); ok {
-      resources = &r
-    }
-  }
-
-  rt := process.Runtime{
-    Outdir: "/cwl",
-  }
-  // TODO related to the resource requirement search above. basically a hack
-  //      for the conformance tests, for now.
-  if resources != nil {
-    rt.Cores = string(resources.CoresMin)
-  }
+// sessionLog reports the endpoints of session jobs on stderr.
+type sessionLog struct{}
 
-  fs := localfs.NewLocal(r.inputsDir)
-  fs.CalcChecksum = true
-  //fs, err := gsfs.NewGS("buchanae-funnel")
-  //if err != nil {
-    //return nil, err
-  //}
+func (sessionLog) Open(ep process.Endpoint) error {
+  fmt.Fprintf(os.Stderr, "Session %s is running at %s\n", ep.JobID, ep.URL)
+  return nil
+}
 
-  proc, err := process.NewProcess(tool, vals, rt, fs)
-  if err != nil {
-    return nil, err
-  }
+func (sessionLog) Close(ep process.Endpoint) error {
+  fmt.Fprintf(os.Stderr, "Session %s has ended\n", ep.JobID)
+  return nil
+}
 
-  cmd, err := proc.Command()
+// Execute runs the command of a bound process via tugboat,
+// and returns the filesystem holding the job's outputs.
+func (r *runner) Execute(proc *process.Process) (process.Filesystem, error) {
+  job, err := process.NewJob(proc)
   if err != nil {
     return nil, err
   }
 
-  //fmt.Fprintln(os.Stderr, cmd)
+  //fmt.Fprintln(os.Stderr, job.Command)
 
-  workdir := "/cwl"
   // TODO necessary for cwl conformance tests
   image := "python:2"
+  if job.Image != "" {
+    image = job.Image
+  }
 
-  if d, ok := tool.RequiresDocker(); ok {
-    image = d.Pull
-    if d.OutputDirectory != "" {
-      workdir = d.OutputDirectory
-    }
+  taskID := "cwl-test1-" + job.ID
+  outdir := r.outdir
+  if r.jobDirs {
+    outdir = filepath.Join(r.outdir, taskID)
   }
 
   task := &tug.Task{
-    ID: "cwl-test1-" + xid.New().String(),
+    ID: taskID,
     ContainerImage: image,
-    Command: cmd,
-    Workdir: workdir,
-    Volumes: []string{workdir, "/tmp"},
-    Env: proc.Env(),
+    Command: job.Command,
+    Workdir: job.Workdir,
+    Volumes: []string{job.Workdir, job.Tmpdir},
+    Env: job.Env,
+    Stdin: job.Stdin,
+    Stdout: job.Stdout,
+    Stderr: job.Stderr,
 
     /* TODO need process.OutputBindings() */
     Outputs: []tug.File{
       {
-        URL: r.outdir,
-        Path: workdir,
+        URL: outdir,
+        Path: job.Workdir,
       },
     },
   }
-  task.Env["HOME"] = workdir
-  task.Env["TMPDIR"] = "/tmp"
 
-  stdout := proc.Stdout()
-  stderr := proc.Stderr()
-  if stdout != "" {
-    task.Stdout = workdir + "/" + stdout
+  stageDir, err := ioutil.TempDir("", "cwl-workdir-")
+  if err != nil {
+    return nil, err
   }
-  if stderr != "" {
-    task.Stderr = workdir + "/" + stderr
+  if !r.debug {
+    defer os.RemoveAll(stageDir)
   }
 
-  files := []cwl.File{}
-  for _, in := range proc.InputBindings() {
-    if f, ok := in.Value.(cwl.File); ok {
-      files = append(files, flattenFiles(f)...)
-    }
+  workdirInputs, err := process.StageWorkDir(job.WorkDir, stageDir)
+  if err != nil {
+    return nil, fmt.Errorf("staging InitialWorkDirRequirement listing: %s", err)
   }
-  for _, f := range files {
-    task.Inputs = append(task.Inputs, tug.File{
-      URL: f.Location,
-      // TODO
-      Path: f.Path,
-    })
+  for _, in := range append(job.Inputs, workdirInputs...) {
+    task.Inputs = append(task.Inputs, tug.File{URL: in.Location, Path: in.Path})
   }
 
   ctx := context.Background()
@@ -195,7 +290,7 @@ func (r *runner) runTool(tool *cwl.Tool, vals cwl.Values) (cwl.Values, error) {
   }
   
   var exec tug.Executor
-  if _, got :=tool.RequiresDocker() ; got {
+  if job.Image != "" {
     exec = &docker.Docker{
       Logger: log,
       NoPull: true,
@@ -212,14 +307,11 @@ func (r *runner) runTool(tool *cwl.Tool, vals cwl.Values) (cwl.Values, error) {
   defer stage.RemoveAll()
 
   err = tug.Run(ctx, task, stage, log, store, exec)
-  if err != nil {
-    if e, ok := err.(*tug.ExecError); ok {
-      for _, code := range tool.SuccessCodes {
-        if e.ExitCode == code {
-          err = nil
-        }
-      }
-    }
+  // Classify the exit code by the tool's success and failure codes.
+  if e, ok := err.(*tug.ExecError); ok {
+    err = proc.ExitError(e.ExitCode)
+  } else if err == nil {
+    err = proc.ExitError(0)
   }
   if err != nil {
     return nil, err
@@ -227,25 +319,11 @@ func (r *runner) runTool(tool *cwl.Tool, vals cwl.Values) (cwl.Values, error) {
 
   //fmt.Println(strings.Join(cmd, " "))
 
-  outfs := localfs.NewLocal(r.outdir)
+
+  outfs := localfs.NewLocal(outdir)
   outfs.CalcChecksum = true
   //outfs, err := gsfs.NewGS("buchanae-cwl-output")
-  if err != nil {
-    return nil, err
-  }
-
-  return proc.Outputs(outfs)
-}
-
-func flattenFiles(file cwl.File) []cwl.File {
-  files := []cwl.File{file}
-  for _, fd := range file.SecondaryFiles {
-    // TODO fix the mismatch between cwl.File and *cwl.File
-    if f, ok := fd.(*cwl.File); ok {
-      files = append(files, flattenFiles(*f)...)
-    }
-  }
-  return files
+  return outfs, nil
 }
 
 
@@ -0,0 +1,66 @@
+package main
+
+import (
+  "fmt"
+  "os"
+  "github.com/lijiang2014/cwl"
+  "github.com/spf13/cobra"
+)
+
+type upgradeOpts struct {
+  version string
+  noResolve bool
+  json bool
+}
+
+func init() {
+  opts := upgradeOpts{
+    version: cwl.Versions[len(cwl.Versions)-1],
+  }
+
+  cmd := &cobra.Command{
+    Use: "upgrade <doc.cwl>",
+    Short: "Upgrade a document to a later CWL version, reporting what needs to be migrated by hand",
+    Args: cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+      return upgrade(opts, args[0])
+    },
+  }
+  root.AddCommand(cmd)
+
+  f := cmd.Flags()
+  f.StringVar(&opts.version, "to", opts.version, "CWL version to upgrade to")
+  f.BoolVar(&opts.noResolve, "no-resolve", opts.noResolve, "keep references to other documents, e.g. step run files, instead of inlining them")
+  f.BoolVar(&opts.json, "json", opts.json, "")
+}
+
+func upgrade(opts upgradeOpts, path string) error {
+  var doc cwl.Document
+  var err error
+
+  if opts.noResolve {
+    doc, err = cwl.LoadWithResolver(path, cwl.NoResolve())
+  } else {
+    doc, err = cwl.Load(path)
+  }
+  if err != nil {
+    return err
+  }
+
+  up, report, err := cwl.Upgrade(doc, opts.version)
+  if err != nil {
+    return err
+  }
+
+  b, err := marshalDoc(up, opts.json)
+  if err != nil {
+    return err
+  }
+  fmt.Println(string(b))
+
+  // The report goes to stderr, so that the document can be redirected to a file.
+  for _, msg := range report {
+    fmt.Fprintf(os.Stderr, "%s: %s\n", path, msg)
+  }
+  return nil
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/go-yaml/yaml"
 	"github.com/kr/pretty"
 )
 
@@ -13,3 +15,18 @@ func errf(msg string, args ...interface{}) error {
 func debug(i ...interface{}) {
 	pretty.Println(i...)
 }
+
+// marshalDoc marshals a document written by pack or upgrade to JSON or YAML.
+// YAML is converted from the JSON, so that the documents' MarshalJSON wrappers
+// apply, e.g. adding the "class" fields.
+func marshalDoc(doc interface{}, asJSON bool) ([]byte, error) {
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil || asJSON {
+		return b, err
+	}
+	var v yaml.MapSlice
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
@@ -24,6 +24,36 @@ const (
 	MergeFlattened                 = "merge_flattened"
 )
 
+// PickValueMethod describes how to pick non-null values from the sources
+// of a step input or workflow output. Added in v1.2.
+type PickValueMethod string
+
+const (
+	FirstNonNull   PickValueMethod = "first_non_null"
+	TheOnlyNonNull PickValueMethod = "the_only_non_null"
+	AllNonNull     PickValueMethod = "all_non_null"
+)
+
+// SecondaryFile is an item of the secondaryFiles of an input or output:
+// a pattern, e.g. ".bai" or "^.fai", or an expression.
+//
+// Since v1.1, Required is true, false or an expression, which defaults
+// to true for inputs and false for outputs. A pattern ending with "?"
+// is loaded with Required false.
+type SecondaryFile struct {
+	Pattern  Expression `json:"pattern,omitempty"`
+	Required Expression `json:"required,omitempty"`
+}
+
+// LoadListing describes how much of a Directory listing to load.
+type LoadListing string
+
+const (
+	NoListing      LoadListing = "no_listing"
+	ShallowListing LoadListing = "shallow_listing"
+	DeepListing    LoadListing = "deep_listing"
+)
+
 type DocumentRef struct {
 	Location string
 }
@@ -84,6 +114,11 @@ type Directory struct {
 func (File) filedir()      {}
 func (Directory) filedir() {}
 
+func (Dirent) initialWorkDirListing()     {}
+func (Expression) initialWorkDirListing() {}
+func (File) initialWorkDirListing()       {}
+func (Directory) initialWorkDirListing()  {}
+
 func (Any) String() string           { return "any" }
 func (Null) String() string          { return "null" }
 func (Boolean) String() string       { return "boolean" }
@@ -128,6 +163,7 @@ type Graph struct {
 func (Tool) Doctype()       string    { return "CommandLineTool" }
 func (Workflow) Doctype() string      { return "Workflow" }
 func (ExpressionTool) Doctype() string { return "ExpressionTool" }
+func (Operation) Doctype() string      { return "Operation" }
 func (DocumentRef) Doctype() string   { return "DocumentRef" }
 func (Graph) Doctype() string { return "$graph" }
 
@@ -214,10 +250,19 @@ func (InlineJavascriptRequirement) requirement()     {}
 func (SchemaDefRequirement) requirement()            {}
 func (SoftwareRequirement) requirement()             {}
 func (InitialWorkDirRequirement) requirement()       {}
+func (LoadListingRequirement) requirement()          {}
+func (ToolTimeLimit) requirement()                   {}
+func (NetworkAccess) requirement()                   {}
+func (WorkReuse) requirement()                       {}
+func (InplaceUpdateRequirement) requirement()        {}
 func (SubworkflowFeatureRequirement) requirement()   {}
 func (ScatterFeatureRequirement) requirement()       {}
 func (MultipleInputFeatureRequirement) requirement() {}
 func (StepInputExpressionRequirement) requirement()  {}
+func (SchedulerRequirement) requirement()            {}
+func (ParallelRequirement) requirement()             {}
+func (KubernetesRequirement) requirement()           {}
+func (SessionRequirement) requirement()              {}
 
 type WorkflowRequirement interface {
 	wfrequirement()
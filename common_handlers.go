@@ -7,6 +7,18 @@ import (
 )
 
 func (l *loader) MappingToDocument(n node) (Document, error) {
+	// Documents without a cwlVersion, e.g. inlined in a step,
+	// have the version of the enclosing document.
+	version := findKey(n, "cwlVersion")
+	if version == "" {
+		version = l.version
+	}
+	if err := checkVersion(version); err != nil {
+		return nil, err
+	}
+	prev := l.version
+	l.version = version
+	defer func() { l.version = prev }()
 
   graphNodes, ok := findValue(n, "$graph")
   if ok {
@@ -18,9 +30,13 @@ func (l *loader) MappingToDocument(n node) (Document, error) {
     if err != nil {
       return nil, err
     }
+    if err := graph.resolve(); err != nil {
+      return nil, err
+    }
     return graph, nil
   }
 
+	var doc Document
 	class := findKey(n, "class")
 	switch strings.ToLower(class) {
 
@@ -29,36 +45,80 @@ func (l *loader) MappingToDocument(n node) (Document, error) {
 		if err := l.load(n, t); err != nil {
 			return nil, err
 		}
-		return t, nil
+		t.CWLVersion = version
+		doc = t
 
 	case "workflow":
 		wf := &Workflow{}
 		if err := l.load(n, wf); err != nil {
 			return nil, err
 		}
-		return wf, nil
+		wf.CWLVersion = version
+		doc = wf
 
 	case "expressiontool":
 		t := &ExpressionTool{}
 		if err := l.load(n, t); err != nil {
 			return nil, err
 		}
-		return t, nil
+		t.CWLVersion = version
+		doc = t
+
+	case "operation":
+		op := &Operation{}
+		if err := l.load(n, op); err != nil {
+			return nil, err
+		}
+		op.CWLVersion = version
+		doc = op
 
 	default:
 		return nil, fmt.Errorf("unknown document class: '%s'", class)
 	}
+
+	if err := checkFeatures(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
 }
 
 func (l *loader) ScalarToDocument(n node) (Document, error) {
+	loc, fragment := splitFragment(n.Value)
+	// References to documents of a packed graph, e.g. "#revtool.cwl",
+	// are resolved once the whole graph is loaded.
+	if loc == "" {
+		return DocumentRef{Location: n.Value}, nil
+	}
 	if _, ok := l.resolver.(noResolver); ok {
 		return DocumentRef{Location: n.Value}, nil
 	}
-	b, base, err := l.resolver.Resolve(l.base, n.Value)
+	b, base, err := l.resolver.Resolve(l.base, loc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve document: %s", err)
 	}
-	return LoadDocumentBytes(b, base, l.resolver)
+	doc, err := LoadDocumentBytes(b, base, l.resolver)
+	if err != nil {
+		return nil, err
+	}
+	return selectEntry(doc, fragment)
+}
+
+func (l *loader) ScalarToSecondaryFileSlice(n node) ([]SecondaryFile, error) {
+	f, err := l.ScalarToSecondaryFile(n)
+	return []SecondaryFile{f}, err
+}
+
+// ScalarToSecondaryFile loads a pattern or expression. Since v1.1,
+// a pattern ending with "?" is an optional file.
+func (l *loader) ScalarToSecondaryFile(n node) (SecondaryFile, error) {
+	pattern := n.Value
+	if strings.HasSuffix(pattern, "?") && normalizeVersion(l.version) != "v1.0" {
+		return SecondaryFile{
+			Pattern:  Expression(strings.TrimSuffix(pattern, "?")),
+			Required: "false",
+		}, nil
+	}
+	return SecondaryFile{Pattern: Expression(pattern)}, nil
 }
 
 func (l *loader) ScalarToExpressionSlice(n node) ([]Expression, error) {
@@ -144,16 +204,46 @@ func (l *loader) MappingToInputFieldSlice(n node) ([]InputField, error) {
 	for _, kv := range itermap(n) {
 		k := kv.k
 		v := kv.v
-		i := InputField{Name: k}
+		i := InputField{}
 		err := l.load(v, &i)
 		if err != nil {
 			return nil, err
 		}
+		i.Name = k
 		fields = append(fields, i)
 	}
 	return fields, nil
 }
 
+func (l *loader) MappingToOutputFieldSlice(n node) ([]OutputField, error) {
+	var fields []OutputField
+
+	for _, kv := range itermap(n) {
+		k := kv.k
+		v := kv.v
+		o := OutputField{}
+		err := l.load(v, &o)
+		if err != nil {
+			return nil, err
+		}
+		o.Name = k
+		fields = append(fields, o)
+	}
+	return fields, nil
+}
+
+func (l *loader) ScalarToInputField(n node) (InputField, error) {
+	f := InputField{}
+	err := l.load(n, &f.Type)
+	return f, err
+}
+
+func (l *loader) ScalarToOutputField(n node) (OutputField, error) {
+	f := OutputField{}
+	err := l.load(n, &f.Type)
+	return f, err
+}
+
 /* Type loading is pretty complex.... */
 
 func (l *loader) MappingToInputTypeSlice(n node) ([]InputType, error) {
@@ -10,10 +10,14 @@ func Load(loc string) (Document, error) {
 	return LoadWithResolver(loc, DefaultResolver{})
 }
 
+// LoadWithResolver loads the document at loc. A fragment selects a document
+// of a packed graph, e.g. "packed.cwl#main". Otherwise a packed document
+// is loaded as a Graph, see Graph.Entry.
 func LoadWithResolver(loc string, r Resolver) (Document, error) {
 	if r == nil {
 		r = NoResolve()
 	}
+	loc, fragment := splitFragment(loc)
 
 	var b []byte
 	var base string
@@ -31,7 +35,11 @@ func LoadWithResolver(loc string, r Resolver) (Document, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve document: %s", err)
 	}
-	return LoadDocumentBytes(b, base, r)
+	doc, err := LoadDocumentBytes(b, base, r)
+	if err != nil || fragment == "" {
+		return doc, err
+	}
+	return selectEntry(doc, fragment)
 }
 
 func LoadDocumentBytes(b []byte, base string, r Resolver) (Document, error) {
@@ -39,7 +47,7 @@ func LoadDocumentBytes(b []byte, base string, r Resolver) (Document, error) {
 		r = NoResolve()
 	}
 
-	l := loader{base, r}
+	l := loader{base: base, resolver: r}
 	// Parse the YAML into an AST
 	yamlnode, err := yamlast.Parse(b)
 	if err != nil {
@@ -3,13 +3,9 @@ package expr
 import (
 	"github.com/lijiang2014/cwl"
 	"github.com/robertkrimen/otto"
-	"regexp"
 	"strings"
 )
 
-// TODO need parser that tracks open/close of parens
-var rx = regexp.MustCompile(`\$\((.*)\)`)
-
 // Part describes a part of a CWL expression string which has been
 // parsed by Parse().
 type Part struct {
@@ -47,12 +43,16 @@ func Parse(expr cwl.Expression) []*Part {
 
 	// parse parameter reference
 	last := 0
-	matches := rx.FindAllStringSubmatchIndex(e, -1)
-	for _, match := range matches {
-		start := match[0]
-		end := match[1]
-		gstart := match[2]
-		gend := match[3]
+	for {
+		i := strings.Index(e[last:], "$(")
+		if i < 0 {
+			break
+		}
+		start := last + i
+		end := closeParen(e, start+1)
+		if end < 0 {
+			break
+		}
 
 		if start > last {
 			parts = append(parts, &Part{
@@ -64,14 +64,14 @@ func Parse(expr cwl.Expression) []*Part {
 
 		parts = append(parts, &Part{
 			Raw:   string(e[start:end]),
-			Expr:  string(e[gstart:gend]),
+			Expr:  string(e[start+2 : end-1]),
 			Start: start,
 			End:   end,
 		})
 		last = end
 	}
 
-	if last < len(e)-1 {
+	if last < len(e) {
 		parts = append(parts, &Part{
 			Raw:   string(e[last:]),
 			Start: last,
@@ -82,6 +82,35 @@ func Parse(expr cwl.Expression) []*Part {
 	return parts
 }
 
+// closeParen returns the index following the parenthesis which closes
+// the one at e[open], or -1 if it isn't closed. Parentheses in string
+// literals, e.g. $(inputs.name + ")"), aren't counted.
+func closeParen(e string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(e); i++ {
+		c := e[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
 // IsExpression returns true if the given string contains a CWL expression.
 func IsExpression(expr cwl.Expression) bool {
 	parts := Parse(expr)
@@ -121,6 +150,15 @@ func EvalParts(parts []*Part, libs []string, data map[string]interface{}) (inter
 		vm.Set(key, val)
 	}
 
+	// Load the expression libraries (InlineJavascriptRequirement.expressionLib)
+	// so that they are available to all parts of the expression.
+	if len(libs) > 0 {
+		_, err := vm.Run(strings.Join(libs, "\n"))
+		if err != nil {
+			return nil, errf("failed to load JS expression library: %s", err)
+		}
+	}
+
 	if len(parts) == 1 {
 		part := parts[0]
 
@@ -131,7 +169,7 @@ func EvalParts(parts []*Part, libs []string, data map[string]interface{}) (inter
 
 		// Expression or JS function body.
 		// Can return any type.
-		code := strings.Join(libs, "\n")
+		var code string
 		if part.IsFuncBody {
 			code = "(function(){" + part.Expr + "})()"
 		} else {
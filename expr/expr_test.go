@@ -2,6 +2,7 @@ package expr
 
 import (
 	"github.com/kr/pretty"
+	"github.com/lijiang2014/cwl"
 	"reflect"
 	"testing"
 )
@@ -20,6 +21,19 @@ func TestParseString(t *testing.T) {
 				{Raw: "none", Start: 0, End: 4},
 			},
 		},
+		{
+			input: "2",
+			expect: []*Part{
+				{Raw: "2", Start: 0, End: 1},
+			},
+		},
+		{
+			input: "$(one)s",
+			expect: []*Part{
+				{Raw: "$(one)", Expr: "one", Start: 0, End: 6},
+				{Raw: "s", Start: 6, End: 7},
+			},
+		},
 		{
 			input: "$(inputs.one.path)",
 			expect: []*Part{
@@ -112,17 +126,18 @@ func TestParseString(t *testing.T) {
 		{
 			input: "${}",
 			expect: []*Part{
-				{Raw: "${}", Expr: "", Start: 0, End: 3},
+				{Raw: "${}", Expr: "", Start: 0, End: 3, IsFuncBody: true},
 			},
 		},
 		{
 			input: "${foo bar $(bas)}",
 			expect: []*Part{
 				{
-					Raw:   "${foo bar $(bas)}",
-					Expr:  "foo bar $(bas)",
-					Start: 0,
-					End:   17,
+					Raw:        "${foo bar $(bas)}",
+					Expr:       "foo bar $(bas)",
+					Start:      0,
+					End:        17,
+					IsFuncBody: true,
 				},
 			},
 		},
@@ -141,11 +156,24 @@ func TestParseString(t *testing.T) {
 			input: "${\n  var r = [];\n  for (var i = 10; i >= 1; i--) {\n    r.push(i);\n  }\n  return r;\n}\n",
 			expect: []*Part{
 				{
-					Raw:   "${\n  var r = [];\n  for (var i = 10; i >= 1; i--) {\n    r.push(i);\n  }\n  return r;\n}\n",
-					Expr:  "var r = [];\n  for (var i = 10; i >= 1; i--) {\n    r.push(i);\n  }\n  return r;",
+					Raw:        "${\n  var r = [];\n  for (var i = 10; i >= 1; i--) {\n    r.push(i);\n  }\n  return r;\n}\n",
+					Expr:       "var r = [];\n  for (var i = 10; i >= 1; i--) {\n    r.push(i);\n  }\n  return r;",
+					Start:      0,
+					End:        84,
+					IsFuncBody: true,
+				},
+			},
+		},
+		{
+			input: `$(inputs.name + ")") after`,
+			expect: []*Part{
+				{
+					Raw:   `$(inputs.name + ")")`,
+					Expr:  `inputs.name + ")"`,
 					Start: 0,
-					End:   84,
+					End:   20,
 				},
+				{Raw: " after", Start: 20, End: 26},
 			},
 		},
 	}
@@ -153,7 +181,7 @@ func TestParseString(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.input, func(t *testing.T) {
 			t.Logf(`input: "%s"`, test.input)
-			parts := Parse(test.input)
+			parts := Parse(cwl.Expression(test.input))
 			if !reflect.DeepEqual(parts, test.expect) {
 				t.Errorf("unexpected matches")
 				for _, d := range pretty.Diff(parts, test.expect) {
@@ -0,0 +1,141 @@
+package cwl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Entry returns the document of a packed graph identified by fragment,
+// e.g. "main" or "#main". An empty fragment selects "#main",
+// or the only document of the graph.
+func (g Graph) Entry(fragment string) (Document, error) {
+	id := strings.TrimPrefix(fragment, "#")
+	if id == "" {
+		if len(g.Docs) == 1 {
+			return g.Docs[0], nil
+		}
+		id = "main"
+	}
+	if doc, ok := g.lookup(id); ok {
+		return doc, nil
+	}
+	return nil, fmt.Errorf(`document "#%s" not found in $graph`, id)
+}
+
+func (g Graph) lookup(id string) (Document, bool) {
+	for _, doc := range g.Docs {
+		if strings.TrimPrefix(docID(doc), "#") == id {
+			return doc, true
+		}
+	}
+	return nil, false
+}
+
+// resolve replaces references to the documents of the graph,
+// e.g. `run: "#revtool.cwl"`, with the documents, and makes the IDs
+// of the inputs and outputs of the graph's tools relative to the tools,
+// e.g. "#revtool.cwl/input" becomes "input".
+func (g Graph) resolve() error {
+	for _, doc := range g.Docs {
+		if err := g.resolveDoc(doc); err != nil {
+			return fmt.Errorf(`resolving "%s": %s`, docID(doc), err)
+		}
+	}
+	return nil
+}
+
+func (g Graph) resolveDoc(doc Document) error {
+	switch z := doc.(type) {
+	case *Tool:
+		for i := range z.Inputs {
+			z.Inputs[i].ID = localID(z.ID, z.Inputs[i].ID)
+		}
+		for i := range z.Outputs {
+			z.Outputs[i].ID = localID(z.ID, z.Outputs[i].ID)
+		}
+	case *ExpressionTool:
+		for i := range z.Inputs {
+			z.Inputs[i].ID = localID(z.ID, z.Inputs[i].ID)
+		}
+		for i := range z.Outputs {
+			z.Outputs[i].ID = localID(z.ID, z.Outputs[i].ID)
+		}
+	case *Operation:
+		for i := range z.Inputs {
+			z.Inputs[i].ID = localID(z.ID, z.Inputs[i].ID)
+		}
+		for i := range z.Outputs {
+			z.Outputs[i].ID = localID(z.ID, z.Outputs[i].ID)
+		}
+	case *Workflow:
+		for i := range z.Steps {
+			step := &z.Steps[i]
+			ref, ok := step.Run.(DocumentRef)
+			if !ok {
+				// Documents inlined in a step may refer to the graph too.
+				if _, ok := step.Run.(Graph); !ok && step.Run != nil {
+					if err := g.resolveDoc(step.Run); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if !strings.HasPrefix(ref.Location, "#") {
+				continue
+			}
+			run, ok := g.lookup(strings.TrimPrefix(ref.Location, "#"))
+			if !ok {
+				return fmt.Errorf(`step "%s": document "%s" not found in $graph`, step.ID, ref.Location)
+			}
+			step.Run = run
+		}
+	}
+	return nil
+}
+
+// selectEntry returns the document identified by fragment:
+// the entry of a packed graph, or the document itself if its ID matches.
+func selectEntry(doc Document, fragment string) (Document, error) {
+	if g, ok := doc.(Graph); ok {
+		return g.Entry(fragment)
+	}
+	id := strings.TrimPrefix(docID(doc), "#")
+	if fragment != "" && id != "" && id != fragment {
+		return nil, fmt.Errorf(`document "#%s" not found`, fragment)
+	}
+	return doc, nil
+}
+
+// splitFragment splits a document location into the location
+// and its fragment, e.g. "packed.cwl#main" into "packed.cwl" and "main".
+func splitFragment(loc string) (string, string) {
+	if i := strings.Index(loc, "#"); i >= 0 {
+		return loc[:i], loc[i+1:]
+	}
+	return loc, ""
+}
+
+// docID returns the ID of a process document.
+func docID(doc Document) string {
+	switch z := doc.(type) {
+	case *Tool:
+		return z.ID
+	case *Workflow:
+		return z.ID
+	case *ExpressionTool:
+		return z.ID
+	case *Operation:
+		return z.ID
+	}
+	return ""
+}
+
+// localID strips the "#" and the ID of the document from the ID
+// of one of its parameters, e.g. "#main/input" becomes "input".
+func localID(docID, id string) string {
+	id = strings.TrimPrefix(id, "#")
+	if docID = strings.TrimPrefix(docID, "#"); docID != "" {
+		id = strings.TrimPrefix(id, docID+"/")
+	}
+	return id
+}
@@ -0,0 +1,125 @@
+package cwl_test
+
+import (
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/process"
+)
+
+const packedDoc = `
+cwlVersion: v1.0
+$graph:
+  - id: "#main"
+    class: Workflow
+    inputs:
+      - id: "#main/n"
+        type: int
+    outputs:
+      - id: "#main/out"
+        type: int
+        outputSource: "#main/twice/out"
+    steps:
+      - id: "#main/once"
+        run: "#inc"
+        in:
+          - id: "#main/once/n"
+            source: "#main/n"
+        out: ["#main/once/out"]
+      - id: "#main/twice"
+        run: "#inc"
+        in:
+          - id: "#main/twice/n"
+            source: "#main/once/out"
+        out: ["#main/twice/out"]
+  - id: "#inc"
+    class: CommandLineTool
+    baseCommand: "true"
+    inputs:
+      - id: "#inc/n"
+        type: int
+    outputs:
+      - id: "#inc/out"
+        type: int
+        outputBinding:
+          outputEval: $(inputs.n + 1)
+`
+
+func TestPackedGraph(t *testing.T) {
+	doc := loadDoc(t, packedDoc)
+	g, ok := doc.(cwl.Graph)
+	if !ok {
+		t.Fatalf("expected a graph, got %T", doc)
+	}
+
+	// The graph runs its "#main" entry.
+	e := &process.Engine{Executor: nopExecutor{}}
+	out, err := e.Run(g, cwl.Values{"n": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["out"] != int32(3) {
+		t.Errorf("expected 3, got %#v", out["out"])
+	}
+
+	// Other documents are selected by fragment.
+	inc, err := g.Entry("#inc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool, ok := inc.(*cwl.Tool)
+	if !ok {
+		t.Fatalf("expected a tool, got %T", inc)
+	}
+	if tool.Inputs[0].ID != "n" || tool.Outputs[0].ID != "out" {
+		t.Errorf("expected IDs relative to the tool, got %q %q", tool.Inputs[0].ID, tool.Outputs[0].ID)
+	}
+	if _, err := g.Entry("missing"); err == nil {
+		t.Error("expected an error for a missing entry")
+	}
+}
+
+func TestPackedGraphMissingRef(t *testing.T) {
+	_, err := cwl.LoadDocumentBytes([]byte(`
+cwlVersion: v1.0
+$graph:
+  - id: "#main"
+    class: Workflow
+    inputs: []
+    outputs: []
+    steps:
+      - id: "#main/step"
+        run: "#missing"
+        in: []
+        out: []
+`), ".", cwl.NoResolve())
+	if err == nil {
+		t.Error("expected an error for a reference missing from the graph")
+	}
+}
+
+func TestLoadPackedFragment(t *testing.T) {
+	doc, err := cwl.Load("examples/107-revsort-packed/tool.cwl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	main, err := doc.(cwl.Graph).Entry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf := main.(*cwl.Workflow)
+	for _, step := range wf.Steps {
+		if _, ok := step.Run.(*cwl.Tool); !ok {
+			t.Errorf("step %s: expected the run reference to be resolved, got %T", step.ID, step.Run)
+		}
+	}
+
+	doc, err = cwl.Load("examples/107-revsort-packed/tool.cwl#sorttool.cwl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool, ok := doc.(*cwl.Tool)
+	if !ok || tool.ID != "#sorttool.cwl" {
+		t.Errorf("expected the sort tool, got %#v", doc)
+	}
+}
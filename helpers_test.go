@@ -0,0 +1,24 @@
+package cwl_test
+
+import (
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/process"
+)
+
+// nopExecutor doesn't run anything. Tools used with it must
+// compute their outputs with outputEval.
+type nopExecutor struct{}
+
+func (nopExecutor) Execute(proc *process.Process) (process.Filesystem, error) {
+	return nil, nil
+}
+
+func loadDoc(t *testing.T, src string) cwl.Document {
+	doc, err := cwl.LoadDocumentBytes([]byte(src), ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
@@ -2,6 +2,7 @@ package cwl
 
 import (
 	"encoding/json"
+	"strconv"
 )
 
 // A bunch of tedious wrappers for fields like "class" and "type"
@@ -52,6 +53,43 @@ func (i OutputRecord) MarshalJSON() ([]byte, error) {
 		Wrap
 	}{"record", Wrap(i)})
 }
+func (i InputEnum) MarshalJSON() ([]byte, error) {
+	type Wrap InputEnum
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Wrap
+	}{"enum", Wrap(i)})
+}
+
+func (i OutputEnum) MarshalJSON() ([]byte, error) {
+	type Wrap OutputEnum
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Wrap
+	}{"enum", Wrap(i)})
+}
+
+// SchemaDef marshals its type's fields inline with its name,
+// e.g. {"name": "HelloType", "type": "record", "fields": [...]}.
+func (x SchemaDef) MarshalJSON() ([]byte, error) {
+	if x.Type == nil {
+		return nil, errf("missing type for schema def %q", x.Name)
+	}
+	t, err := json.Marshal(x.Type)
+	if err != nil {
+		return nil, err
+	}
+	name, err := json.Marshal(x.Name)
+	if err != nil {
+		return nil, err
+	}
+	b := append([]byte(`{"name":`), name...)
+	if len(t) > 2 {
+		b = append(b, ',')
+	}
+	return append(b, t[1:]...), nil
+}
+
 func (x Workflow) MarshalJSON() ([]byte, error) {
 	type Wrap Workflow
 	return json.Marshal(struct {
@@ -68,6 +106,19 @@ func (x Tool) MarshalJSON() ([]byte, error) {
 	}{"CommandLineTool", Wrap(x)})
 }
 
+// UnknownRequirement marshals its class and the fields it was loaded with.
+// An unresolved $import has no class.
+func (x UnknownRequirement) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{}
+	for k, v := range x.Fields {
+		m[k] = v
+	}
+	if x.Name != "" {
+		m["class"] = x.Name
+	}
+	return json.Marshal(m)
+}
+
 func (x DockerRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap DockerRequirement
 	return json.Marshal(struct {
@@ -145,6 +196,13 @@ func (x MultipleInputFeatureRequirement) MarshalJSON() ([]byte, error) {
 		Wrap
 	}{"MultipleInputFeatureRequirement", Wrap(x)})
 }
+func (x LoadListingRequirement) MarshalJSON() ([]byte, error) {
+	type Wrap LoadListingRequirement
+	return json.Marshal(struct {
+		Class string `json:"class"`
+		Wrap
+	}{"LoadListingRequirement", Wrap(x)})
+}
 func (x StepInputExpressionRequirement) MarshalJSON() ([]byte, error) {
 	type Wrap StepInputExpressionRequirement
 	return json.Marshal(struct {
@@ -152,3 +210,127 @@ func (x StepInputExpressionRequirement) MarshalJSON() ([]byte, error) {
 		Wrap
 	}{"StepInputExpressionRequirement", Wrap(x)})
 }
+func (x ExpressionTool) MarshalJSON() ([]byte, error) {
+	type Wrap ExpressionTool
+	return json.Marshal(struct {
+		Class string `json:"class"`
+		Wrap
+	}{"ExpressionTool", Wrap(x)})
+}
+
+// Graph has no class of its own; its cwlVersion defaults to
+// the version of its entry, since the documents are marshaled with theirs.
+func (x Graph) MarshalJSON() ([]byte, error) {
+	type Wrap Graph
+	if x.CWLVersion == "" && len(x.Docs) > 0 {
+		if entry, err := x.Entry(""); err == nil {
+			x.CWLVersion = cwlVersion(entry)
+		}
+	}
+	return json.Marshal(Wrap(x))
+}
+func (x SchedulerRequirement) MarshalJSON() ([]byte, error) {
+	type Wrap SchedulerRequirement
+	return json.Marshal(struct {
+		Class string `json:"class"`
+		Wrap
+	}{"SchedulerRequirement", Wrap(x)})
+}
+func (x ParallelRequirement) MarshalJSON() ([]byte, error) {
+	type Wrap ParallelRequirement
+	return json.Marshal(struct {
+		Class string `json:"class"`
+		Wrap
+	}{"ParallelRequirement", Wrap(x)})
+}
+func (x KubernetesRequirement) MarshalJSON() ([]byte, error) {
+	type Wrap KubernetesRequirement
+	return json.Marshal(struct {
+		Class string `json:"class"`
+		Wrap
+	}{"KubernetesRequirement", Wrap(x)})
+}
+func (x SessionRequirement) MarshalJSON() ([]byte, error) {
+	type Wrap SessionRequirement
+	return json.Marshal(struct {
+		Class string `json:"class"`
+		Wrap
+	}{"SessionRequirement", Wrap(x)})
+}
+func (x ToolTimeLimit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Class     string      `json:"class"`
+		TimeLimit interface{} `json:"timelimit,omitempty"`
+	}{"ToolTimeLimit", intLiteral(x.TimeLimit)})
+}
+func (x NetworkAccess) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Class         string      `json:"class"`
+		NetworkAccess interface{} `json:"networkAccess,omitempty"`
+	}{"NetworkAccess", boolLiteral(x.NetworkAccess)})
+}
+func (x WorkReuse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Class       string      `json:"class"`
+		EnableReuse interface{} `json:"enableReuse,omitempty"`
+	}{"WorkReuse", boolLiteral(x.EnableReuse)})
+}
+func (x InplaceUpdateRequirement) MarshalJSON() ([]byte, error) {
+	type Wrap InplaceUpdateRequirement
+	return json.Marshal(struct {
+		Class string `json:"class"`
+		Wrap
+	}{"InplaceUpdateRequirement", Wrap(x)})
+}
+func (x Operation) MarshalJSON() ([]byte, error) {
+	type Wrap Operation
+	return json.Marshal(struct {
+		Class string `json:"class"`
+		Wrap
+	}{"Operation", Wrap(x)})
+}
+
+// SecondaryFile marshals as its pattern, unless Required is set,
+// so that v1.0 documents stay valid v1.0.
+func (x SecondaryFile) MarshalJSON() ([]byte, error) {
+	if x.Required == "" {
+		return json.Marshal(x.Pattern)
+	}
+	return json.Marshal(struct {
+		Pattern  Expression  `json:"pattern"`
+		Required interface{} `json:"required"`
+	}{x.Pattern, boolLiteral(x.Required)})
+}
+
+// OptOut marshals as a boolean, rather than the text of MarshalText.
+func (x OptOut) MarshalJSON() ([]byte, error) {
+	return json.Marshal(x.Value())
+}
+
+// intLiteral returns the value of an int|Expression field, so that
+// e.g. 60 marshals as a number rather than a string.
+// Expressions are returned as is.
+func intLiteral(e Expression) interface{} {
+	if e == "" {
+		return nil
+	}
+	if i, err := strconv.ParseInt(string(e), 10, 64); err == nil {
+		return i
+	}
+	return e
+}
+
+// boolLiteral returns the value of a boolean|Expression field, so that
+// true and false marshal as booleans rather than strings.
+// Expressions are returned as is.
+func boolLiteral(e Expression) interface{} {
+	switch e {
+	case "":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return e
+}
@@ -18,6 +18,9 @@ import (
 type loader struct {
 	base     string
 	resolver Resolver
+	// version is the cwlVersion of the document being loaded,
+	// which documents inlined in it inherit.
+	version string
 }
 
 // load is given a YAML node and a destination type,
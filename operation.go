@@ -0,0 +1,18 @@
+package cwl
+
+// Operation is an abstract process, added in v1.2, which describes
+// the inputs and outputs of a step without an implementation.
+// Operations can't be run, but may be replaced by an implementation
+// before a workflow is run.
+type Operation struct {
+	CWLVersion string `json:"cwlVersion,omitempty"`
+	ID         string `json:"id,omitempty"`
+	Label      string `json:"label,omitempty"`
+	Doc        string `json:"doc,omitempty"`
+
+	Hints        []Requirement `json:"hints,omitempty"`
+	Requirements []Requirement `json:"requirements,omitempty"`
+
+	Inputs  []CommandInput  `json:"inputs,omitempty"`
+	Outputs []CommandOutput `json:"outputs,omitempty"`
+}
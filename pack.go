@@ -0,0 +1,225 @@
+package cwl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pack packs a document and the documents run by its steps into a Graph,
+// whose "#main" entry is the document. The IDs of the documents and their
+// parameters are rewritten to unique fragments, e.g. "#main/input", and
+// steps refer to the documents they run by fragment, e.g. `run: "#revtool"`.
+//
+// The document must be loaded with a resolver, e.g. by Load, so that
+// references to other files, including $import and $include directives
+// and SchemaDefRequirement types, are inlined. The document isn't modified.
+func Pack(doc Document) (Graph, error) {
+	p := packer{
+		ids:    map[string]bool{},
+		packed: map[Document]string{},
+	}
+	if _, err := p.pack(doc, "main"); err != nil {
+		return Graph{}, err
+	}
+	p.graph.CWLVersion = cwlVersion(p.graph.Docs[0])
+	return p.graph, nil
+}
+
+type packer struct {
+	graph Graph
+	// ids holds the IDs of the packed documents, without "#".
+	ids map[string]bool
+	// packed maps the documents already packed to their IDs,
+	// so that a document run by several steps is packed once.
+	packed map[Document]string
+}
+
+// pack adds a document to the graph with a unique ID derived from name,
+// and returns the document's ID.
+func (p *packer) pack(doc Document, name string) (string, error) {
+	switch z := doc.(type) {
+	case DocumentRef:
+		return "", errf(`unresolved document reference "%s"`, z.Location)
+	case Graph:
+		entry, err := z.Entry("")
+		if err != nil {
+			return "", err
+		}
+		return p.pack(entry, name)
+	case nil:
+		return "", errf("missing document")
+	}
+	if id, ok := p.packed[doc]; ok {
+		return id, nil
+	}
+
+	id := p.uniqueID(name)
+	p.packed[doc] = id
+	// Reserve the document's place, so that the graph lists documents
+	// before the documents run by their steps.
+	i := len(p.graph.Docs)
+	p.graph.Docs = append(p.graph.Docs, nil)
+
+	var packed Document
+	switch z := doc.(type) {
+	case *Tool:
+		t := *z
+		t.ID = "#" + id
+		t.Inputs = append([]CommandInput(nil), z.Inputs...)
+		for j := range t.Inputs {
+			t.Inputs[j].ID = packedID(id, localID(z.ID, t.Inputs[j].ID))
+		}
+		t.Outputs = append([]CommandOutput(nil), z.Outputs...)
+		for j := range t.Outputs {
+			t.Outputs[j].ID = packedID(id, localID(z.ID, t.Outputs[j].ID))
+		}
+		packed = &t
+
+	case *ExpressionTool:
+		t := *z
+		t.ID = "#" + id
+		t.Inputs = append([]CommandInput(nil), z.Inputs...)
+		for j := range t.Inputs {
+			t.Inputs[j].ID = packedID(id, localID(z.ID, t.Inputs[j].ID))
+		}
+		t.Outputs = append([]CommandOutput(nil), z.Outputs...)
+		for j := range t.Outputs {
+			t.Outputs[j].ID = packedID(id, localID(z.ID, t.Outputs[j].ID))
+		}
+		packed = &t
+
+	case *Operation:
+		op := *z
+		op.ID = "#" + id
+		op.Inputs = append([]CommandInput(nil), z.Inputs...)
+		for j := range op.Inputs {
+			op.Inputs[j].ID = packedID(id, localID(z.ID, op.Inputs[j].ID))
+		}
+		op.Outputs = append([]CommandOutput(nil), z.Outputs...)
+		for j := range op.Outputs {
+			op.Outputs[j].ID = packedID(id, localID(z.ID, op.Outputs[j].ID))
+		}
+		packed = &op
+
+	case *Workflow:
+		wf, err := p.packWorkflow(z, id)
+		if err != nil {
+			return "", err
+		}
+		packed = wf
+
+	default:
+		return "", errf(`can't pack document type "%s"`, doc.Doctype())
+	}
+
+	p.graph.Docs[i] = packed
+	return id, nil
+}
+
+func (p *packer) packWorkflow(z *Workflow, id string) (*Workflow, error) {
+	wf := *z
+	wf.ID = "#" + id
+	// sources rewrites the IDs of sources, e.g. "step/out",
+	// to fragments, e.g. "#main/step/out".
+	sources := func(src []string) []string {
+		var out []string
+		for _, s := range src {
+			out = append(out, packedID(id, localID(z.ID, s)))
+		}
+		return out
+	}
+
+	wf.Inputs = append([]WorkflowInput(nil), z.Inputs...)
+	for i := range wf.Inputs {
+		wf.Inputs[i].ID = packedID(id, localID(z.ID, wf.Inputs[i].ID))
+	}
+	wf.Outputs = append([]WorkflowOutput(nil), z.Outputs...)
+	for i := range wf.Outputs {
+		out := &wf.Outputs[i]
+		out.ID = packedID(id, localID(z.ID, out.ID))
+		out.OutputSource = sources(out.OutputSource)
+	}
+
+	wf.Steps = append([]Step(nil), z.Steps...)
+	for i := range wf.Steps {
+		step := &wf.Steps[i]
+		stepID := localID(z.ID, step.ID)
+		step.ID = packedID(id, stepID)
+		// stepParam rewrites the ID of a step parameter, e.g. "in"
+		// or "#main/step/in", to "#main/step/in".
+		stepParam := func(param string) string {
+			return packedID(id, stepID+"/"+strings.TrimPrefix(localID(z.ID, param), stepID+"/"))
+		}
+
+		step.In = append([]StepInput(nil), step.In...)
+		for j := range step.In {
+			step.In[j].ID = stepParam(step.In[j].ID)
+			step.In[j].Source = sources(step.In[j].Source)
+		}
+		step.Out = append([]StepOutput(nil), step.Out...)
+		for j := range step.Out {
+			step.Out[j].ID = stepParam(step.Out[j].ID)
+		}
+		var scatter []string
+		for _, s := range step.Scatter {
+			scatter = append(scatter, stepParam(s))
+		}
+		step.Scatter = scatter
+
+		// Documents are named after their IDs, or the steps running them.
+		name := localID("", docID(step.Run))
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		if name == "" {
+			name = stepID
+		}
+		runID, err := p.pack(step.Run, name)
+		if err != nil {
+			return nil, errf(`step "%s": %s`, stepID, err)
+		}
+		step.Run = DocumentRef{Location: "#" + runID}
+	}
+	return &wf, nil
+}
+
+// uniqueID returns name, or name with a numeric suffix
+// if a packed document already has that ID.
+func (p *packer) uniqueID(name string) string {
+	id := name
+	for i := 2; p.ids[id]; i++ {
+		id = fmt.Sprintf("%s_%d", name, i)
+	}
+	p.ids[id] = true
+	return id
+}
+
+// packedID returns the fragment of a parameter of a packed document.
+func packedID(docID, param string) string {
+	return "#" + docID + "/" + param
+}
+
+// cwlVersion returns the cwlVersion of a process document.
+func cwlVersion(doc Document) string {
+	switch z := doc.(type) {
+	case *Tool:
+		return z.CWLVersion
+	case *Workflow:
+		return z.CWLVersion
+	case *ExpressionTool:
+		return z.CWLVersion
+	case *Operation:
+		return z.CWLVersion
+	}
+	return ""
+}
+
+// PackFile loads the document at the given location with the resolver
+// and packs it. See Pack.
+func PackFile(loc string, r Resolver) (Graph, error) {
+	doc, err := LoadWithResolver(loc, r)
+	if err != nil {
+		return Graph{}, err
+	}
+	return Pack(doc)
+}
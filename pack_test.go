@@ -0,0 +1,165 @@
+package cwl_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/process"
+)
+
+var packFiles = map[string]string{
+	"wf.cwl": `
+cwlVersion: v1.0
+class: Workflow
+requirements:
+  - $import: types.yml
+hints:
+  cwltool:TimeLimit:
+    timelimit: 60
+  vendor:Options:
+    count: "60"
+    enabled: "true"
+    ids: [1, "2", true]
+inputs:
+  n: int
+  mode: "#Mode"
+outputs:
+  out:
+    type: int
+    outputSource: twice/out
+steps:
+  once:
+    run: inc.cwl
+    in:
+      n: n
+    out: [out]
+  twice:
+    run:
+      class: CommandLineTool
+      id: inc.cwl
+      baseCommand: "true"
+      inputs:
+        n: int
+      outputs:
+        out:
+          type: int
+          outputBinding:
+            outputEval: $(inputs.n + 1)
+    in:
+      n: once/out
+    out: [out]
+`,
+	"inc.cwl": `
+cwlVersion: v1.0
+class: CommandLineTool
+id: inc.cwl
+baseCommand: "true"
+inputs:
+  n: int
+outputs:
+  out:
+    type: int
+    outputBinding:
+      outputEval: $(inputs.n + 1)
+`,
+	"types.yml": `
+class: SchemaDefRequirement
+types:
+  - name: Mode
+    type: enum
+    symbols: [fast, slow]
+`,
+}
+
+func TestPack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cwl-pack-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, src := range packFiles {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := cwl.PackFile(filepath.Join(dir, "wf.cwl"), cwl.DefaultResolver{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unknown hints keep their fields.
+	if !strings.Contains(string(b), `{"class":"cwltool:TimeLimit","timelimit":60}`) {
+		t.Errorf("expected the cwltool:TimeLimit hint to be packed with its fields, got %s", b)
+	}
+	// Quoted scalars stay strings.
+	if !strings.Contains(string(b), `{"class":"vendor:Options","count":"60","enabled":"true","ids":[1,"2",true]}`) {
+		t.Errorf("expected the vendor:Options hint to be packed as written, got %s", b)
+	}
+
+	// The packed document doesn't refer to other files.
+	os.RemoveAll(dir)
+	doc, err := cwl.LoadDocumentBytes(b, dir, cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, ok := doc.(cwl.Graph)
+	if !ok {
+		t.Fatalf("expected a graph, got %T", doc)
+	}
+	if packed.CWLVersion != "v1.0" || len(packed.Docs) != 3 {
+		t.Fatalf("unexpected graph: %s", b)
+	}
+
+	main, err := packed.Entry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wf := main.(*cwl.Workflow)
+	if wf.ID != "#main" || wf.Steps[1].In[0].Source[0] != "#main/once/out" {
+		t.Errorf("expected IDs rewritten to fragments, got %s", b)
+	}
+	// Both tools are named "inc.cwl", so the second is renamed.
+	ids := []string{wf.Steps[0].Run.(*cwl.Tool).ID, wf.Steps[1].Run.(*cwl.Tool).ID}
+	if ids[0] != "#inc.cwl" || ids[1] != "#inc.cwl_2" {
+		t.Errorf("expected unique document IDs, got %v", ids)
+	}
+	if _, ok := wf.RequiresSchemaDef(); !ok {
+		t.Error("expected the imported SchemaDefRequirement to be packed")
+	}
+
+	e := &process.Engine{Executor: nopExecutor{}}
+	out, err := e.Run(packed, cwl.Values{"n": 1, "mode": "fast"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["out"] != int32(3) {
+		t.Errorf("expected 3, got %#v", out["out"])
+	}
+}
+
+func TestPackUnresolved(t *testing.T) {
+	doc := loadDoc(t, `
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs: []
+steps:
+  step:
+    run: missing.cwl
+    in: []
+    out: []
+`)
+	if _, err := cwl.Pack(doc); err == nil {
+		t.Error("expected an error for an unresolved run reference")
+	}
+}
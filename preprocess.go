@@ -25,7 +25,8 @@ func (l *loader) preprocess(n node) (node, error) {
 					return nil, err
 				}
 				// TODO set line/col/file of the new nodes
-				return yamlnode.Children[0], nil
+				// The imported document may have directives of its own.
+				return l.preprocess(yamlnode.Children[0])
 
 			case "$include":
 	      if _, ok := l.resolver.(noResolver); ok {
@@ -12,11 +12,9 @@ import (
 func (process *Process) Command() ([]string, error) {
 
 	// Copy "Tool.Inputs" bindings
-	args := make([]*Binding, 0, len(process.bindings))
-	for _, b := range process.bindings {
-		if b.clb != nil {
-			args = append(args, b)
-		}
+	args, err := process.argBindings(process.bindings)
+	if err != nil {
+		return nil, err
 	}
 
 	// Add "Tool.Arguments"
@@ -24,20 +22,13 @@ func (process *Process) Command() ([]string, error) {
 		if arg.ValueFrom == "" {
 			return nil, errf("valueFrom is required but missing for argument %d", i)
 		}
-		args = append(args, &Binding{
+		b, err := process.evalBinding(&Binding{
 			arg, argType{}, nil, sortKey{arg.Position}, nil, "",
 		})
-	}
-
-	// Evaluate "valueFrom" expression.
-	for _, b := range args {
-		if b.clb.GetValueFrom() != "" {
-			val, err := process.eval(b.clb.GetValueFrom(), b.Value)
-			if err != nil {
-				return nil, errf("failed to eval argument value: %s", err)
-			}
-			b.Value = val
+		if err != nil {
+			return nil, err
 		}
+		args = append(args, b)
 	}
 
 	sort.Stable(bySortKey(args))
@@ -57,6 +48,56 @@ func (process *Process) Command() ([]string, error) {
 	return cmd, nil
 }
 
+// argBindings returns copies of the bindings which add command line arguments,
+// with their "valueFrom" expressions evaluated. The process bindings aren't
+// modified, so that Command may be called more than once.
+func (process *Process) argBindings(bindings []*Binding) ([]*Binding, error) {
+	var out []*Binding
+	for _, b := range bindings {
+		if _, ok := b.Type.(cwl.InputRecord); ok && b.clb == nil {
+			// The fields of a record without an input binding are sorted
+			// alongside the bindings containing the record.
+			nested, err := process.argBindings(b.nested)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, nested...)
+			continue
+		}
+		if b.clb == nil {
+			continue
+		}
+		x, err := process.evalBinding(b)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, x)
+	}
+	return out, nil
+}
+
+// evalBinding returns a copy of the binding with its "valueFrom" expression evaluated.
+// The field bindings of a record are evaluated and sorted too.
+func (process *Process) evalBinding(b *Binding) (*Binding, error) {
+	x := *b
+	if b.clb.GetValueFrom() != "" {
+		val, err := process.eval(b.clb.GetValueFrom(), b.Value)
+		if err != nil {
+			return nil, errf("failed to eval argument value: %s", err)
+		}
+		x.Value = val
+	}
+	if _, ok := b.Type.(cwl.InputRecord); ok {
+		nested, err := process.argBindings(b.nested)
+		if err != nil {
+			return nil, err
+		}
+		sort.Stable(bySortKey(nested))
+		x.nested = nested
+	}
+	return &x, nil
+}
+
 // args converts a binding into a list of formatted command line arguments.
 func bindArgs(b *Binding) []string {
 	switch b.Type.(type) {
@@ -93,10 +134,22 @@ func bindArgs(b *Binding) []string {
 		}
 
 	case cwl.InputRecord:
-		// TODO
+		// cwl spec:
+		// "record: Add prefix only, and recursively add object fields for
+		// which inputBinding is specified."
+		//
+		// Unless the record was replaced by the result of "valueFrom".
+		if b.clb.GetValueFrom() != "" {
+			return formatArgs(b.clb, b.Value)
+		}
+		args := formatArgs(b.clb)
+		for _, nb := range b.nested {
+			args = append(args, bindArgs(nb)...)
+		}
+		return args
 
 	case cwl.Any, cwl.String, cwl.Int, cwl.Long, cwl.Float, cwl.Double, cwl.FileType,
-		cwl.DirectoryType, argType:
+		cwl.DirectoryType, cwl.InputEnum, argType:
 		return formatArgs(b.clb, b.Value)
 
 	case cwl.Boolean:
@@ -0,0 +1,190 @@
+package process
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lijiang2014/cwl"
+)
+
+// Cache stores the output objects of tool jobs. Jobs are identified by
+// a key computed by CacheKey, so a job identical to a previous job can
+// reuse its outputs without running.
+//
+// Cached outputs reference the files produced by the original job,
+// so those files must outlive the cache entries.
+type Cache interface {
+	// Get returns the outputs stored for the key, if any.
+	Get(key string) (cwl.Values, bool, error)
+	// Put stores the outputs of the job identified by the key.
+	Put(key string, outputs cwl.Values) error
+}
+
+// CacheKey computes a key identifying the job of a bound process.
+// The key is a hash of the tool document, the input object, the command line,
+// the environment, the container image and the locations and checksums
+// of the input files. The input object is included since expressions,
+// e.g. in outputEval, may depend on inputs which aren't on the command line.
+//
+// Input files are identified by their checksum when the filesystem
+// provides one, e.g. local.Local with CalcChecksum enabled.
+func CacheKey(proc *Process) (string, error) {
+	tool, err := json.Marshal(proc.Tool())
+	if err != nil {
+		return "", wrap(err, "marshaling tool document")
+	}
+
+	inputs, err := json.Marshal(proc.inputs)
+	if err != nil {
+		return "", wrap(err, "marshaling input object")
+	}
+
+	cmd, err := proc.Command()
+	if err != nil {
+		return "", err
+	}
+
+	// The job's directories may differ between runs of the same job,
+	// e.g. with Engine.JobDirs, so they're replaced by placeholders.
+	// The job is identified by its inputs and input file locations instead.
+	dirs := jobDirs(proc.runtime)
+	for i, arg := range cmd {
+		cmd[i] = dirs.Replace(arg)
+	}
+	env := map[string]string{}
+	for k, v := range proc.Env() {
+		env[k] = dirs.Replace(v)
+	}
+
+	var image string
+	if d, ok := proc.Tool().RequiresDocker(); ok {
+		image = d.Pull
+		if d.ImageID != "" {
+			image = d.ImageID
+		}
+	}
+
+	var files []string
+	for _, b := range proc.InputBindings() {
+		files = append(files, bindingFiles(b)...)
+	}
+	sort.Strings(files)
+
+	b, err := json.Marshal(struct {
+		Tool    json.RawMessage
+		Inputs  json.RawMessage
+		Command []string
+		Env     map[string]string
+		Image   string
+		Files   []string
+	}{tool, inputs, cmd, env, image, files})
+	if err != nil {
+		return "", wrap(err, "marshaling cache key")
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b)), nil
+}
+
+// jobDirs returns a replacer of the runtime directories of a job
+// with placeholders. The outdir and tmpdir come first, since they may
+// be inside RootHost.
+func jobDirs(rt Runtime) *strings.Replacer {
+	var pairs []string
+	for _, d := range []struct{ dir, placeholder string }{
+		{rt.Outdir, "$(runtime.outdir)"},
+		{rt.Tmpdir, "$(runtime.tmpdir)"},
+		{rt.RootHost, "$(runtime.roothost)"},
+	} {
+		if d.dir != "" {
+			pairs = append(pairs, d.dir, d.placeholder)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// bindingFiles lists the location and checksum of every file
+// bound by an input binding and its nested bindings.
+func bindingFiles(b *Binding) []string {
+	files := valueFiles(b.Value)
+	for _, n := range b.nested {
+		files = append(files, bindingFiles(n)...)
+	}
+	return files
+}
+
+func valueFiles(v cwl.Value) []string {
+	var files []string
+	switch z := v.(type) {
+	case *cwl.File:
+		files = append(files, valueFiles(*z)...)
+	case cwl.File:
+		files = append(files, z.Location+" "+z.Checksum)
+		for _, sec := range z.SecondaryFiles {
+			files = append(files, valueFiles(sec)...)
+		}
+	case []cwl.Value:
+		for _, x := range z {
+			files = append(files, valueFiles(x)...)
+		}
+	case map[string]cwl.Value:
+		for _, x := range z {
+			files = append(files, valueFiles(x)...)
+		}
+	}
+	return files
+}
+
+// DirCache is a Cache which stores each output object
+// as a JSON file in a local directory.
+type DirCache struct {
+	dir string
+}
+
+// NewDirCache creates a Cache in the directory at `dir`,
+// creating the directory if it doesn't exist.
+func NewDirCache(dir string) (*DirCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, wrap(err, "creating cache directory")
+	}
+	return &DirCache{dir: dir}, nil
+}
+
+func (c *DirCache) Get(key string) (cwl.Values, bool, error) {
+	b, err := ioutil.ReadFile(c.path(key))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, wrap(err, "reading cache entry")
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, false, wrap(err, "parsing cache entry %s", key)
+	}
+	vals, err := toValues(obj)
+	if err != nil {
+		return nil, false, wrap(err, "loading cache entry %s", key)
+	}
+	return vals, true, nil
+}
+
+func (c *DirCache) Put(key string, outputs cwl.Values) error {
+	b, err := json.MarshalIndent(outputs, "", "  ")
+	if err != nil {
+		return wrap(err, "marshaling outputs")
+	}
+	if err := writeFileAtomic(c.path(key), b); err != nil {
+		return wrap(err, "writing cache entry")
+	}
+	return nil
+}
+
+func (c *DirCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
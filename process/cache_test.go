@@ -0,0 +1,125 @@
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+func TestCache(t *testing.T) {
+	doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+baseCommand: cat
+inputs:
+  f:
+    type: File
+    inputBinding: {}
+  n: int
+outputs:
+  out:
+    type: int
+    outputBinding:
+      outputEval: $(inputs.n + 1)
+`)
+	tool := doc.(*cwl.Tool)
+
+	dir, err := ioutil.TempDir("", "cwl-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache, err := NewDirCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := checksumFS{"/data/in.txt": "sha1$aaa"}
+	exec := &countExecutor{}
+	e := &Engine{Executor: exec, Filesystem: fs, Cache: cache}
+
+	run := func(n int, expectRuns int32) {
+		inputs := cwl.Values{
+			"f": cwl.File{Location: "/data/in.txt"},
+			"n": n,
+		}
+		out, err := e.RunTool(tool, inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exec.runs != expectRuns {
+			t.Errorf("expected %d runs, got %d", expectRuns, exec.runs)
+		}
+		if v, _ := toFloat(out["out"]); v != float64(n+1) {
+			t.Errorf("expected out %d, got %#v", n+1, out["out"])
+		}
+	}
+
+	run(1, 1)
+	// Identical job.
+	run(1, 1)
+	// Different input value.
+	run(2, 2)
+	// Same input values, but the input file content changed.
+	fs["/data/in.txt"] = "sha1$bbb"
+	run(1, 3)
+}
+
+func TestCacheJobDirs(t *testing.T) {
+	doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  EnvVarRequirement:
+    envDef:
+      OUT: $(runtime.outdir)
+baseCommand: cat
+arguments: [$(runtime.outdir)/out.txt, $(runtime.tmpdir)]
+inputs:
+  f:
+    type: File
+    inputBinding: {}
+outputs: []
+`)
+	dir, err := ioutil.TempDir("", "cwl-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache, err := NewDirCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec := &countExecutor{}
+	e := &Engine{
+		Executor:   exec,
+		Filesystem: checksumFS{"/data/in.txt": "sha1$aaa"},
+		Cache:      cache,
+		JobDirs:    filepath.Join(dir, "jobs"),
+	}
+
+	// Each run gets its own job directories, but is the same job.
+	for i := 0; i < 2; i++ {
+		_, err := e.RunTool(doc.(*cwl.Tool), cwl.Values{"f": cwl.File{Location: "/data/in.txt"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exec.runs != 1 {
+		t.Errorf("expected the second run to hit the cache, got %d runs", exec.runs)
+	}
+}
+
+func toFloat(v cwl.Value) (float64, bool) {
+	switch z := v.(type) {
+	case int32:
+		return float64(z), true
+	case float64:
+		return z, true
+	}
+	return 0, false
+}
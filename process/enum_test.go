@@ -0,0 +1,63 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+func TestEnumBinding(t *testing.T) {
+	doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+baseCommand: echo
+inputs:
+  mode:
+    type:
+      type: enum
+      symbols: ["#mode/fast", "#mode/slow"]
+      inputBinding:
+        prefix: --mode
+  level:
+    type:
+      type: enum
+      symbols: [low, high]
+    inputBinding:
+      position: 1
+outputs:
+  out:
+    type:
+      type: enum
+      symbols: [low, high]
+    outputBinding:
+      outputEval: $(inputs.level)
+`)
+	tool := doc.(*cwl.Tool)
+
+	proc, err := NewProcess(tool, cwl.Values{"mode": "slow", "level": "high"}, Runtime{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := proc.Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"echo", "--mode", "slow", "high"}
+	if !reflect.DeepEqual(cmd, expect) {
+		t.Errorf("expected %v, got %v", expect, cmd)
+	}
+
+	out, err := proc.Outputs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["out"] != "high" {
+		t.Errorf("expected enum output, got %#v", out["out"])
+	}
+
+	_, err = NewProcess(tool, cwl.Values{"mode": "medium", "level": "high"}, Runtime{}, nil)
+	if err == nil {
+		t.Error("expected error for a value which isn't an enum symbol")
+	}
+}
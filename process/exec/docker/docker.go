@@ -0,0 +1,207 @@
+// Package docker runs the jobs of CommandLineTools in docker containers,
+// using the docker command line client.
+package docker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lijiang2014/cwl/process"
+	localfs "github.com/lijiang2014/cwl/process/fs/local"
+)
+
+// Docker runs commands in docker containers. The job's working and temporary
+// directories are host directories mounted in the container, and inputs are
+// mounted read-only at their paths.
+type Docker struct {
+	// Dir is the host directory holding a directory per job.
+	// Defaults to a new temporary directory per job.
+	Dir string
+	// DefaultImage is used by jobs which don't require a docker image.
+	DefaultImage string
+	// Command is the docker client command. Defaults to "docker".
+	Command string
+	// CalcChecksum calculates the checksums of output files.
+	CalcChecksum bool
+}
+
+func (d *Docker) Prepare(job *process.Job) error {
+	if d.Dir != "" {
+		job.HostDir = filepath.Join(d.Dir, job.ID)
+	} else {
+		dir, err := ioutil.TempDir("", "cwl-docker-")
+		if err != nil {
+			return err
+		}
+		job.HostDir = dir
+	}
+	for _, dir := range []string{"workdir", "tmp", "stage"} {
+		if err := os.MkdirAll(filepath.Join(job.HostDir, dir), 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Docker) Stage(job *process.Job) error {
+	// Inputs are mounted when the container is run. The listing
+	// is prepared on the host and mounted with the other inputs.
+	workdir, err := process.StageWorkDir(job.WorkDir, filepath.Join(job.HostDir, "stage"))
+	if err != nil {
+		return fmt.Errorf("staging InitialWorkDirRequirement listing: %s", err)
+	}
+	job.Inputs = append(job.Inputs, workdir...)
+	return nil
+}
+
+// Args returns the arguments of the docker command running the job.
+func (d *Docker) Args(job *process.Job) ([]string, error) {
+	image := job.Image
+	if image == "" {
+		image = d.DefaultImage
+	}
+	if image == "" {
+		return nil, fmt.Errorf("no docker image")
+	}
+
+	args := []string{"run", "--rm", "-i",
+		"-w", job.Workdir,
+		"-v", filepath.Join(job.HostDir, "workdir") + ":" + job.Workdir + ":rw",
+		"-v", filepath.Join(job.HostDir, "tmp") + ":" + job.Tmpdir + ":rw",
+	}
+	for _, in := range job.Inputs {
+		src, err := process.HostPath(in.Location)
+		if err != nil {
+			return nil, err
+		}
+		mode := "ro"
+		if in.Writable {
+			mode = "rw"
+		}
+		args = append(args, "-v", src+":"+in.Path+":"+mode)
+	}
+
+	var env []string
+	for k, v := range job.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+
+	// The endpoint of a session job is published on the host.
+	if job.Session != nil {
+		port := strconv.Itoa(job.Session.Port)
+		args = append(args, "-p", port+":"+port)
+	}
+
+	args = append(args, image)
+	return append(args, job.Command...), nil
+}
+
+func (d *Docker) Run(job *process.Job) (int, error) {
+	args, err := d.Args(job)
+	if err != nil {
+		return 0, err
+	}
+	command := d.Command
+	if command == "" {
+		command = "docker"
+	}
+	cmd := exec.Command(command, args...)
+
+	// The standard streams of the container are those of the docker client,
+	// so they are redirected from and to host files.
+	if job.Stdin != "" {
+		path, err := d.hostPath(job, job.Stdin)
+		if err != nil {
+			return 0, err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		cmd.Stdin = f
+	}
+	if job.Stdout != "" {
+		path, err := d.hostPath(job, job.Stdout)
+		if err != nil {
+			return 0, err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		cmd.Stdout = f
+	}
+	if job.Stderr != "" {
+		path, err := d.hostPath(job, job.Stderr)
+		if err != nil {
+			return 0, err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		cmd.Stderr = f
+	}
+
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	job.Running("localhost")
+
+	err = cmd.Wait()
+	if e, ok := err.(*exec.ExitError); ok {
+		return e.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// hostPath returns the host path of a path in the container,
+// which must be in the working directory or an input.
+func (d *Docker) hostPath(job *process.Job, path string) (string, error) {
+	if rel, ok := under(path, job.Workdir); ok {
+		return filepath.Join(job.HostDir, "workdir", rel), nil
+	}
+	for _, in := range job.Inputs {
+		if rel, ok := under(path, in.Path); ok {
+			src, err := process.HostPath(in.Location)
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(src, rel), nil
+		}
+	}
+	return "", fmt.Errorf("%q is not mounted in the container", path)
+}
+
+// under returns the path relative to dir, if path is dir or inside it.
+func under(path, dir string) (string, bool) {
+	if path == dir {
+		return "", true
+	}
+	if strings.HasPrefix(path, dir+"/") {
+		return strings.TrimPrefix(path, dir+"/"), true
+	}
+	return "", false
+}
+
+func (d *Docker) Outputs(job *process.Job) (process.Filesystem, error) {
+	fs := localfs.NewLocal(filepath.Join(job.HostDir, "workdir"))
+	fs.CalcChecksum = d.CalcChecksum
+	return fs, nil
+}
@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/process"
+	localfs "github.com/lijiang2014/cwl/process/fs/local"
+)
+
+// fakeDocker records its arguments, copies its stdin to stdout
+// and exits with the code in $FAKE_DOCKER_EXIT.
+const fakeDocker = `#!/bin/sh
+echo "$@" > "$FAKE_DOCKER_ARGS"
+cat
+exit ${FAKE_DOCKER_EXIT:-0}
+`
+
+func TestDocker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cwl-docker-exec-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	command := filepath.Join(dir, "docker")
+	if err := ioutil.WriteFile(command, []byte(fakeDocker), 0755); err != nil {
+		t.Fatal(err)
+	}
+	argsPath := filepath.Join(dir, "args")
+	os.Setenv("FAKE_DOCKER_ARGS", argsPath)
+	defer os.Unsetenv("FAKE_DOCKER_ARGS")
+
+	in := filepath.Join(dir, "in.txt")
+	if err := ioutil.WriteFile(in, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := cwl.LoadDocumentBytes([]byte(`
+class: CommandLineTool
+cwlVersion: v1.0
+hints:
+  DockerRequirement:
+    dockerPull: debian:stretch-slim
+inputs:
+  in: File
+outputs:
+  out:
+    type: File
+    outputBinding:
+      glob: out.txt
+baseCommand: cat
+stdin: $(inputs.in.path)
+stdout: out.txt
+`), ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Docker{Dir: filepath.Join(dir, "jobs"), Command: command}
+	e := &process.Engine{
+		Filesystem: localfs.NewLocal(dir),
+		Runtime:    process.Runtime{Outdir: "/cwl"},
+		Executor:   process.NewToolExecutor(d),
+	}
+	out, err := e.RunTool(doc.(*cwl.Tool), cwl.Values{
+		"in": cwl.File{Location: in, Path: in},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, ok := out["out"].(cwl.File)
+	if !ok {
+		t.Fatalf("expected a File output, got %#v", out["out"])
+	}
+	b, err := ioutil.ReadFile(strings.TrimPrefix(f.Location, "file://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Errorf("expected the stdin file in stdout, got %q", b)
+	}
+
+	args, err := ioutil.ReadFile(argsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"run --rm -i -w /cwl",
+		":/cwl:rw",
+		in + ":/inputs" + in + ":ro",
+		"-e HOME=/cwl",
+		"debian:stretch-slim cat",
+	} {
+		if !strings.Contains(string(args), expect) {
+			t.Errorf("expected %q in docker arguments: %s", expect, args)
+		}
+	}
+
+	// The exit code of the container is the job's exit code.
+	os.Setenv("FAKE_DOCKER_EXIT", "2")
+	defer os.Unsetenv("FAKE_DOCKER_EXIT")
+	_, err = e.RunTool(doc.(*cwl.Tool), cwl.Values{
+		"in": cwl.File{Location: in, Path: in},
+	})
+	if e, ok := err.(*process.ExitError); !ok || e.Code != 2 {
+		t.Errorf("expected exit code 2, got %v", err)
+	}
+}
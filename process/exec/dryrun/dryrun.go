@@ -0,0 +1,116 @@
+// Package dryrun provides an executor which records the jobs
+// it would run, without running anything.
+package dryrun
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/process"
+)
+
+// DryRun records jobs instead of running them. Every command succeeds
+// without producing any files, so tool outputs are only bound by
+// expressions which don't depend on output files.
+type DryRun struct {
+	// Out, if non-nil, receives a description of each job.
+	Out io.Writer
+
+	mtx  sync.Mutex
+	jobs []*process.Job
+}
+
+// Jobs returns the jobs run so far.
+func (d *DryRun) Jobs() []*process.Job {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return append([]*process.Job(nil), d.jobs...)
+}
+
+func (d *DryRun) Prepare(job *process.Job) error {
+	return nil
+}
+
+func (d *DryRun) Stage(job *process.Job) error {
+	return nil
+}
+
+func (d *DryRun) Run(job *process.Job) (int, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.jobs = append(d.jobs, job)
+	if d.Out != nil {
+		describe(d.Out, job)
+	}
+	return 0, nil
+}
+
+func (d *DryRun) Outputs(job *process.Job) (process.Filesystem, error) {
+	return emptyFS{}, nil
+}
+
+// describe writes a description of the job.
+func describe(w io.Writer, job *process.Job) {
+	fmt.Fprintf(w, "job %s\n", job.ID)
+	if job.Image != "" {
+		fmt.Fprintf(w, "  image: %s\n", job.Image)
+	}
+	fmt.Fprintf(w, "  workdir: %s\n", job.Workdir)
+	fmt.Fprintf(w, "  command: %s\n", strings.Join(job.Command, " "))
+
+	var env []string
+	for k, v := range job.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	for _, e := range env {
+		fmt.Fprintf(w, "  env: %s\n", e)
+	}
+
+	for _, in := range job.Inputs {
+		fmt.Fprintf(w, "  input: %s -> %s\n", in.Location, in.Path)
+	}
+	for _, e := range job.WorkDir {
+		fmt.Fprintf(w, "  workdir entry: %s\n", e.Path)
+	}
+	if job.Stdin != "" {
+		fmt.Fprintf(w, "  stdin: %s\n", job.Stdin)
+	}
+	if job.Stdout != "" {
+		fmt.Fprintf(w, "  stdout: %s\n", job.Stdout)
+	}
+	if job.Stderr != "" {
+		fmt.Fprintf(w, "  stderr: %s\n", job.Stderr)
+	}
+}
+
+// emptyFS is the output filesystem of a job which wasn't run.
+type emptyFS struct{}
+
+func (emptyFS) Create(path, contents string) (cwl.File, error) {
+	return cwl.File{}, fmt.Errorf("dry run: can't create %s", path)
+}
+
+func (emptyFS) Info(loc string) (cwl.File, error) {
+	return cwl.File{}, process.ErrFileNotFound
+}
+
+func (emptyFS) Contents(loc string) (string, error) {
+	return "", process.ErrFileNotFound
+}
+
+func (emptyFS) Glob(pattern string) ([]cwl.FileDir, error) {
+	return nil, nil
+}
+
+func (emptyFS) DirInfo(loc string) (cwl.Directory, error) {
+	return cwl.Directory{}, process.ErrFileNotFound
+}
+
+func (emptyFS) List(loc string) ([]cwl.FileDir, error) {
+	return nil, process.ErrFileNotFound
+}
@@ -0,0 +1,97 @@
+package dryrun_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/process"
+	"github.com/lijiang2014/cwl/process/exec/dryrun"
+	localfs "github.com/lijiang2014/cwl/process/fs/local"
+)
+
+const sortTool = `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  InitialWorkDirRequirement:
+    listing:
+      - entryname: keys.txt
+        entry: "2"
+inputs:
+  in: File
+  reverse:
+    type: boolean
+    inputBinding:
+      prefix: -r
+outputs:
+  out:
+    type: string
+    outputBinding:
+      outputEval: $(inputs.in.basename)
+baseCommand: sort
+arguments: [-k, keys.txt]
+stdin: $(inputs.in.path)
+stdout: sorted.txt
+`
+
+func TestDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cwl-dryrun-exec-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.txt")
+	if err := ioutil.WriteFile(in, []byte("b\na\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := cwl.LoadDocumentBytes([]byte(sortTool), ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	d := &dryrun.DryRun{Out: &buf}
+	e := &process.Engine{
+		Runtime:    process.Runtime{Outdir: "/out"},
+		Filesystem: localfs.NewLocal(dir),
+		Executor:   process.NewToolExecutor(d),
+	}
+	out, err := e.RunTool(doc.(*cwl.Tool), cwl.Values{
+		"in":      cwl.File{Location: in},
+		"reverse": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Outputs which don't depend on output files are still bound.
+	if out["out"] != "in.txt" {
+		t.Errorf("expected out in.txt, got %#v", out["out"])
+	}
+
+	jobs := d.Jobs()
+	if len(jobs) != 1 {
+		t.Fatalf("expected one job, got %d", len(jobs))
+	}
+	expected := strings.Join([]string{
+		"job " + jobs[0].ID,
+		"  workdir: /out",
+		"  command: sort -k keys.txt -r",
+		"  env: HOME=/out",
+		"  env: TMPDIR=/tmp",
+		"  input: file://" + in + " -> /inputs" + in,
+		"  workdir entry: /out/keys.txt",
+		"  stdin: /inputs" + in,
+		"  stdout: /out/sorted.txt",
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,113 @@
+// Package local runs the jobs of CommandLineTools as processes on the host.
+package local
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/lijiang2014/cwl/process"
+	localfs "github.com/lijiang2014/cwl/process/fs/local"
+)
+
+// Local runs commands on the host. Job paths are host paths, so the runtime
+// of the processes must use host directories, see process.Engine.JobDirs.
+// Input files are symlinked at their paths.
+type Local struct {
+	// CalcChecksum calculates the checksums of output files.
+	CalcChecksum bool
+}
+
+func (l *Local) Prepare(job *process.Job) error {
+	job.HostDir = job.Workdir
+	for _, dir := range []string{job.Workdir, job.Tmpdir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Local) Stage(job *process.Job) error {
+	// The listing is prepared in place.
+	workdir, err := process.StageWorkDir(job.WorkDir, "/")
+	if err != nil {
+		return fmt.Errorf("staging InitialWorkDirRequirement listing: %s", err)
+	}
+
+	for _, in := range append(job.Inputs, workdir...) {
+		src, err := process.HostPath(in.Location)
+		if err != nil {
+			return err
+		}
+		if src == in.Path {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(in.Path), 0755); err != nil {
+			return err
+		}
+		if err := os.Symlink(src, in.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Local) Run(job *process.Job) (int, error) {
+	if len(job.Command) == 0 {
+		return 0, fmt.Errorf("empty command")
+	}
+
+	cmd := exec.Command(job.Command[0], job.Command[1:]...)
+	cmd.Dir = job.Workdir
+	cmd.Env = os.Environ()
+	for k, v := range job.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	if job.Stdin != "" {
+		f, err := os.Open(job.Stdin)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		cmd.Stdin = f
+	}
+	if job.Stdout != "" {
+		f, err := os.Create(job.Stdout)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		cmd.Stdout = f
+	}
+	if job.Stderr != "" {
+		f, err := os.Create(job.Stderr)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		cmd.Stderr = f
+	}
+
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	job.Running("localhost")
+
+	err := cmd.Wait()
+	if e, ok := err.(*exec.ExitError); ok {
+		return e.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+func (l *Local) Outputs(job *process.Job) (process.Filesystem, error) {
+	fs := localfs.NewLocal(job.Workdir)
+	fs.CalcChecksum = l.CalcChecksum
+	return fs, nil
+}
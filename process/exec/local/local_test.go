@@ -0,0 +1,110 @@
+package local_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/process"
+	"github.com/lijiang2014/cwl/process/exec/local"
+	localfs "github.com/lijiang2014/cwl/process/fs/local"
+)
+
+const catTool = `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  InitialWorkDirRequirement:
+    listing:
+      - entryname: suffix.txt
+        entry: " world"
+inputs:
+  in:
+    type: File
+    inputBinding:
+      position: 1
+outputs:
+  out:
+    type: File
+    outputBinding:
+      glob: out.txt
+baseCommand: cat
+arguments:
+  - position: 2
+    valueFrom: suffix.txt
+stdout: out.txt
+`
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cwl-local-exec-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.txt")
+	if err := ioutil.WriteFile(in, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := cwl.LoadDocumentBytes([]byte(catTool), ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &process.Engine{
+		Filesystem: localfs.NewLocal(dir),
+		Executor:   process.NewToolExecutor(&local.Local{}),
+		JobDirs:    filepath.Join(dir, "jobs"),
+	}
+	out, err := e.RunTool(doc.(*cwl.Tool), cwl.Values{
+		"in": cwl.File{Location: in},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, ok := out["out"].(cwl.File)
+	if !ok {
+		t.Fatalf("expected a File output, got %#v", out["out"])
+	}
+	b, err := ioutil.ReadFile(strings.TrimPrefix(f.Location, "file://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello world" {
+		t.Errorf("unexpected output %q", b)
+	}
+}
+
+func TestLocalExitCode(t *testing.T) {
+	doc, err := cwl.LoadDocumentBytes([]byte(`
+class: CommandLineTool
+cwlVersion: v1.0
+inputs: []
+outputs: []
+baseCommand: ["sh", "-c", "exit 3"]
+temporaryFailCodes: [3]
+`), ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "cwl-local-exec-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	e := &process.Engine{
+		Executor: process.NewToolExecutor(&local.Local{}),
+		JobDirs:  dir,
+	}
+	_, err = e.RunTool(doc.(*cwl.Tool), cwl.Values{})
+	if process.StatusOf(err) != process.TemporaryFail {
+		t.Errorf("expected temporaryFail, got %v", err)
+	}
+}
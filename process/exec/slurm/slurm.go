@@ -0,0 +1,261 @@
+// Package slurm runs the jobs of CommandLineTools as Slurm batch jobs,
+// using the sbatch, squeue and sacct commands.
+package slurm
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lijiang2014/cwl/process"
+	"github.com/lijiang2014/cwl/process/exec/local"
+	localfs "github.com/lijiang2014/cwl/process/fs/local"
+)
+
+// Slurm submits commands as batch jobs. Like the local executor, job paths
+// are host paths, which must be shared with the compute nodes,
+// see process.Engine.JobDirs.
+type Slurm struct {
+	// Dir is the directory holding the batch script and log of each job.
+	// Defaults to a new temporary directory per job.
+	Dir string
+	// Partition is the default partition, used by jobs
+	// which don't require a partition.
+	Partition string
+	// Launcher wraps the commands of MPI jobs. Defaults to "srun".
+	Launcher string
+	// Poll is the interval between job state queries. Defaults to 5 seconds.
+	Poll time.Duration
+	// Sbatch, Squeue and Sacct are the Slurm commands.
+	// Default to "sbatch", "squeue" and "sacct".
+	Sbatch, Squeue, Sacct string
+	// CalcChecksum calculates the checksums of output files.
+	CalcChecksum bool
+}
+
+func (s *Slurm) Prepare(job *process.Job) error {
+	if job.Scheduler.Name != "" && !strings.EqualFold(job.Scheduler.Name, "slurm") {
+		return fmt.Errorf("job requires the %q scheduler", job.Scheduler.Name)
+	}
+	if err := (&local.Local{}).Prepare(job); err != nil {
+		return err
+	}
+	if s.Dir != "" {
+		job.HostDir = filepath.Join(s.Dir, job.ID)
+		return os.MkdirAll(job.HostDir, 0755)
+	}
+	dir, err := ioutil.TempDir("", "cwl-slurm-")
+	if err != nil {
+		return err
+	}
+	job.HostDir = dir
+	return nil
+}
+
+func (s *Slurm) Stage(job *process.Job) error {
+	return (&local.Local{}).Stage(job)
+}
+
+// Script returns the batch script of the job.
+func (s *Slurm) Script(job *process.Job) (string, error) {
+	if len(job.Command) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	directive := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, "#SBATCH "+format+"\n", args...)
+	}
+	directive("--job-name=cwl-%s", job.ID)
+	directive("--chdir=%s", job.Workdir)
+	directive("--output=%s", filepath.Join(job.HostDir, "slurm.log"))
+	if job.Resources.CoresMin > 0 {
+		directive("--cpus-per-task=%d", job.Resources.CoresMin)
+	}
+	if job.Resources.RAMMin > 0 {
+		directive("--mem=%dM", job.Resources.RAMMin)
+	}
+
+	sched := job.Scheduler
+	partition := sched.Partition
+	if partition == "" {
+		partition = s.Partition
+	}
+	if partition != "" {
+		directive("--partition=%s", partition)
+	}
+	if sched.Cluster != "" {
+		directive("--clusters=%s", sched.Cluster)
+	}
+	if sched.Nodes > 0 {
+		directive("--nodes=%d", sched.Nodes)
+	}
+	for _, arg := range sched.Args {
+		directive("%s", arg)
+	}
+	b.WriteString("\n")
+
+	var env []string
+	for k, v := range job.Env {
+		env = append(env, "export "+k+"="+quote(v))
+	}
+	sort.Strings(env)
+	for _, e := range env {
+		b.WriteString(e + "\n")
+	}
+	b.WriteString("cd " + quote(job.Workdir) + " || exit 1\n")
+
+	var cmd []string
+	if sched.MPI {
+		launcher := s.Launcher
+		if launcher == "" {
+			launcher = "srun"
+		}
+		cmd = append(cmd, launcher)
+	}
+	for _, arg := range job.Command {
+		cmd = append(cmd, quote(arg))
+	}
+	if job.Stdin != "" {
+		cmd = append(cmd, "<", quote(job.Stdin))
+	}
+	if job.Stdout != "" {
+		cmd = append(cmd, ">", quote(job.Stdout))
+	}
+	if job.Stderr != "" {
+		cmd = append(cmd, "2>", quote(job.Stderr))
+	}
+	b.WriteString(strings.Join(cmd, " ") + "\n")
+	return b.String(), nil
+}
+
+// quote quotes a string for the shell.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func (s *Slurm) Run(job *process.Job) (int, error) {
+	script, err := s.Script(job)
+	if err != nil {
+		return 0, err
+	}
+	path := filepath.Join(job.HostDir, "job.sh")
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		return 0, err
+	}
+
+	out, err := s.command(s.Sbatch, "sbatch", "--parsable", path)
+	if err != nil {
+		return 0, err
+	}
+	// sbatch --parsable prints "jobid" or "jobid;cluster".
+	id := strings.SplitN(strings.TrimSpace(out), ";", 2)[0]
+	if id == "" {
+		return 0, fmt.Errorf("sbatch didn't print a job ID")
+	}
+
+	var clusters []string
+	if job.Scheduler.Cluster != "" {
+		clusters = []string{"--clusters", job.Scheduler.Cluster}
+	}
+
+	poll := s.Poll
+	if poll == 0 {
+		poll = 5 * time.Second
+	}
+	for {
+		// squeue lists the job until it's finished,
+		// with the nodes allocated to the job once it's running.
+		args := append([]string{"--noheader", "--format=%T|%N", "--jobs", id}, clusters...)
+		out, err := s.command(s.Squeue, "squeue", args...)
+		if err != nil {
+			return 0, err
+		}
+		out = strings.TrimSpace(out)
+		if out == "" {
+			break
+		}
+		fields := strings.SplitN(out, "|", 2)
+		if fields[0] == "RUNNING" && len(fields) == 2 {
+			job.Running(firstNode(fields[1]))
+		}
+		time.Sleep(poll)
+	}
+
+	args := append([]string{"--noheader", "--parsable2", "--format=State,ExitCode", "--jobs", id}, clusters...)
+	out, err = s.command(s.Sacct, "sacct", args...)
+	if err != nil {
+		return 0, err
+	}
+	return exitCode(id, out)
+}
+
+// exitCode parses the exit code of a finished job from the output of sacct,
+// whose first line describes the job, e.g. "FAILED|2:0".
+func exitCode(id, out string) (int, error) {
+	line := strings.SplitN(strings.TrimSpace(out), "\n", 2)[0]
+	fields := strings.Split(line, "|")
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("unexpected sacct output for job %s: %q", id, line)
+	}
+	state := strings.Fields(fields[0])
+	if len(state) == 0 {
+		return 0, fmt.Errorf("unexpected sacct output for job %s: %q", id, line)
+	}
+	switch state[0] {
+	case "COMPLETED", "FAILED":
+	default:
+		// The command didn't run to completion, e.g. it was cancelled,
+		// ran out of time or memory, or its node failed.
+		return 0, fmt.Errorf("slurm job %s %s", id, strings.ToLower(state[0]))
+	}
+	code, err := strconv.Atoi(strings.SplitN(fields[1], ":", 2)[0])
+	if err != nil {
+		return 0, fmt.Errorf("unexpected exit code for job %s: %q", id, fields[1])
+	}
+	return code, nil
+}
+
+// firstNode returns the first node of a Slurm node list,
+// e.g. "cn01" for "cn[01-04,08],gpu1".
+func firstNode(nodes string) string {
+	i := strings.IndexAny(nodes, "[,")
+	if i < 0 || nodes[i] == ',' {
+		return strings.SplitN(nodes, ",", 2)[0]
+	}
+	prefix := nodes[:i]
+	rest := nodes[i+1:]
+	if j := strings.IndexAny(rest, "-,]"); j >= 0 {
+		rest = rest[:j]
+	}
+	return prefix + rest
+}
+
+// command runs a Slurm command, which defaults to name, and returns its output.
+func (s *Slurm) command(command, name string, args ...string) (string, error) {
+	if command == "" {
+		command = name
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(command, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %s: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+func (s *Slurm) Outputs(job *process.Job) (process.Filesystem, error) {
+	fs := localfs.NewLocal(job.Workdir)
+	fs.CalcChecksum = s.CalcChecksum
+	return fs, nil
+}
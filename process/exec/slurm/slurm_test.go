@@ -0,0 +1,211 @@
+package slurm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/process"
+	localfs "github.com/lijiang2014/cwl/process/fs/local"
+)
+
+// The fake Slurm commands run the batch script when it's submitted,
+// and report it as running once before it's finished.
+var fakeCommands = map[string]string{
+	"sbatch": `#!/bin/sh
+for arg; do script=$arg; done
+cp "$script" "$FAKE_SLURM/job.sh"
+sh "$script" > /dev/null 2>&1
+echo $? > "$FAKE_SLURM/exit"
+echo "42;cluster"
+`,
+	"squeue": `#!/bin/sh
+echo "$@" > "$FAKE_SLURM/squeue.args"
+if [ ! -e "$FAKE_SLURM/polled" ]; then
+  touch "$FAKE_SLURM/polled"
+  echo "RUNNING|cn[01-04]"
+fi
+`,
+	"sacct": `#!/bin/sh
+code=$(cat "$FAKE_SLURM/exit")
+if [ "$code" = 0 ]; then
+  echo "COMPLETED|0:0"
+else
+  echo "FAILED|$code:0"
+fi
+echo "COMPLETED|0:0"
+`,
+	"srun": `#!/bin/sh
+echo srun > "$FAKE_SLURM/launcher"
+exec "$@"
+`,
+}
+
+const mpiTool = `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  SchedulerRequirement:
+    scheduler: slurm
+    cluster: hpc
+    partition: $(inputs.queue)
+    nodes: $(inputs.nodes)
+    args: ["--exclusive"]
+  ParallelRequirement:
+    mpiEnabled: true
+  ResourceRequirement:
+    coresMin: 2
+inputs:
+  queue: string
+  nodes: int
+  message:
+    type: string
+    inputBinding: {}
+outputs:
+  out:
+    type: stdout
+baseCommand: echo
+stdout: out.txt
+`
+
+func setup(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cwl-slurm-exec-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(dir, "bin")
+	if err := os.Mkdir(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, script := range fakeCommands {
+		if err := ioutil.WriteFile(filepath.Join(bin, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	os.Setenv("FAKE_SLURM", dir)
+	return dir
+}
+
+func TestSlurm(t *testing.T) {
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	doc, err := cwl.LoadDocumentBytes([]byte(mpiTool), ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &process.Engine{
+		Filesystem: localfs.NewLocal(dir),
+		Executor:   process.NewToolExecutor(&Slurm{Poll: time.Millisecond}),
+		JobDirs:    filepath.Join(dir, "jobs"),
+	}
+	out, err := e.RunTool(doc.(*cwl.Tool), cwl.Values{
+		"queue":   "batch",
+		"nodes":   4,
+		"message": "hello",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, ok := out["out"].(cwl.File)
+	if !ok {
+		t.Fatalf("expected a File output, got %#v", out["out"])
+	}
+	b, err := ioutil.ReadFile(strings.TrimPrefix(f.Location, "file://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello\n" {
+		t.Errorf("unexpected output %q", b)
+	}
+
+	script, err := ioutil.ReadFile(filepath.Join(dir, "job.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"#SBATCH --cpus-per-task=2\n",
+		"#SBATCH --partition=batch\n",
+		"#SBATCH --clusters=hpc\n",
+		"#SBATCH --nodes=4\n",
+		"#SBATCH --exclusive\n",
+		"srun 'echo' 'hello' > ",
+	} {
+		if !strings.Contains(string(script), expect) {
+			t.Errorf("expected %q in batch script:\n%s", expect, script)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "launcher")); err != nil {
+		t.Errorf("expected the command to be launched with srun: %s", err)
+	}
+
+	args, err := ioutil.ReadFile(filepath.Join(dir, "squeue.args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "--jobs 42 --clusters hpc") {
+		t.Errorf("unexpected squeue arguments: %s", args)
+	}
+}
+
+func TestSlurmExitCode(t *testing.T) {
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	doc, err := cwl.LoadDocumentBytes([]byte(`
+class: CommandLineTool
+cwlVersion: v1.0
+inputs: []
+outputs: []
+baseCommand: ["sh", "-c", "exit 3"]
+temporaryFailCodes: [3]
+`), ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &process.Engine{
+		Executor: process.NewToolExecutor(&Slurm{Poll: time.Millisecond}),
+		JobDirs:  filepath.Join(dir, "jobs"),
+	}
+	_, err = e.RunTool(doc.(*cwl.Tool), cwl.Values{})
+	if process.StatusOf(err) != process.TemporaryFail {
+		t.Errorf("expected temporaryFail, got %v", err)
+	}
+}
+
+func TestFirstNode(t *testing.T) {
+	for list, expect := range map[string]string{
+		"cn01":              "cn01",
+		"cn01,cn02":         "cn01",
+		"cn[01-04,08],gpu1": "cn01",
+		"cn[08,01-04]":      "cn08",
+	} {
+		if node := firstNode(list); node != expect {
+			t.Errorf("expected %q for %q, got %q", expect, list, node)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if code, err := exitCode("1", "FAILED|2:0\nFAILED|2:0\n"); err != nil || code != 2 {
+		t.Errorf("expected exit code 2, got %d %v", code, err)
+	}
+	if _, err := exitCode("1", "TIMEOUT|0:0\n"); err == nil {
+		t.Error("expected an error for a job which timed out")
+	}
+	if _, err := exitCode("1", "CANCELLED by 0|0:15\n"); err == nil {
+		t.Error("expected an error for a cancelled job")
+	}
+}
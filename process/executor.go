@@ -0,0 +1,93 @@
+package process
+
+// Executor runs jobs, e.g. as local processes or in docker containers.
+// See Execute for the order in which the methods are called.
+type Executor interface {
+	// Prepare creates the job's working and temporary directories,
+	// and may set the job's HostDir.
+	Prepare(job *Job) error
+	// Stage makes the job's inputs and InitialWorkDirRequirement listing
+	// available to the command at their paths.
+	Stage(job *Job) error
+	// Run runs the job's command with its environment and standard streams,
+	// and returns the command's exit code. An error means the command
+	// couldn't be run at all.
+	Run(job *Job) (int, error)
+	// Outputs returns the filesystem holding the files produced by the command,
+	// which is used to bind the tool's outputs.
+	Outputs(job *Job) (Filesystem, error)
+}
+
+// Execute runs the command of a bound process with an Executor.
+// The exit code of the command is classified by the tool's success
+// and failure codes, see Process.ExitError.
+func Execute(e Executor, proc *Process) (Filesystem, error) {
+	return ExecuteSession(e, nil, proc)
+}
+
+// ExecuteSession is like Execute. If the process is a session tool,
+// its endpoint is opened in s when the executor reports that the job
+// is running, and closed when the job ends. s may be nil.
+func ExecuteSession(e Executor, s Sessions, proc *Process) (Filesystem, error) {
+	job, err := NewJob(proc)
+	if err != nil {
+		return nil, err
+	}
+
+	var ep *Endpoint
+	var openErr error
+	if job.Session != nil && s != nil {
+		job.running = func(host string) {
+			x := newEndpoint(job, host)
+			if openErr = s.Open(x); openErr == nil {
+				ep = &x
+			}
+		}
+	}
+
+	if err := e.Prepare(job); err != nil {
+		return nil, wrap(err, "preparing job")
+	}
+	if err := e.Stage(job); err != nil {
+		return nil, wrap(err, "staging inputs")
+	}
+	code, err := e.Run(job)
+	if ep != nil {
+		if err := s.Close(*ep); err != nil && openErr == nil {
+			openErr = wrap(err, "closing session endpoint")
+		}
+	} else if openErr != nil {
+		openErr = wrap(openErr, "opening session endpoint")
+	}
+	if err != nil {
+		return nil, wrap(err, "running command")
+	}
+	if openErr != nil {
+		return nil, openErr
+	}
+	if err := proc.ExitError(code); err != nil {
+		return nil, err
+	}
+	return e.Outputs(job)
+}
+
+// NewToolExecutor returns a ToolExecutor which runs processes with
+// an Executor, for use as Engine.Executor.
+func NewToolExecutor(e Executor) ToolExecutor {
+	return toolExecutor{e: e}
+}
+
+// NewSessionExecutor is like NewToolExecutor, and records the endpoints
+// of session jobs in s, see ExecuteSession.
+func NewSessionExecutor(e Executor, s Sessions) ToolExecutor {
+	return toolExecutor{e: e, s: s}
+}
+
+type toolExecutor struct {
+	e Executor
+	s Sessions
+}
+
+func (t toolExecutor) Execute(proc *Process) (Filesystem, error) {
+	return ExecuteSession(t.e, t.s, proc)
+}
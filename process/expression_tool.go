@@ -0,0 +1,134 @@
+package process
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/lijiang2014/cwl"
+)
+
+// ExpressionProcess binds an ExpressionTool to concrete input values.
+// Inputs are bound exactly as they are for a CommandLineTool, and the
+// output object is the result of evaluating the tool's expression.
+type ExpressionProcess struct {
+	tool *cwl.ExpressionTool
+	proc *Process
+}
+
+func NewExpressionProcess(tool *cwl.ExpressionTool, values cwl.Values, rt Runtime, fs Filesystem) (*ExpressionProcess, error) {
+	// An ExpressionTool is a CommandLineTool without a command line,
+	// so reuse the CommandLineTool input binding and requirements code.
+	proc, err := NewProcess(&cwl.Tool{
+		CWLVersion:   tool.CWLVersion,
+		ID:           tool.ID,
+		Label:        tool.Label,
+		Doc:          tool.Doc,
+		Hints:        tool.Hints,
+		Requirements: tool.Requirements,
+		Inputs:       tool.Inputs,
+		Outputs:      tool.Outputs,
+	}, values, rt, fs)
+	if err != nil {
+		return nil, err
+	}
+	return &ExpressionProcess{tool: tool, proc: proc}, nil
+}
+
+func (p *ExpressionProcess) Tool() *cwl.ExpressionTool {
+	return p.tool
+}
+
+func (p *ExpressionProcess) InputBindings() []*Binding {
+	return p.proc.InputBindings()
+}
+
+// Outputs evaluates the tool's expression and type checks the resulting
+// object against the tool's output descriptors.
+func (p *ExpressionProcess) Outputs() (cwl.Values, error) {
+	res, err := p.proc.eval(p.tool.Expression, nil)
+	if err != nil {
+		return nil, wrap(err, "evaluating expression")
+	}
+
+	obj, ok := res.(map[string]interface{})
+	if !ok {
+		return nil, errf("expression must return an object, got %#v", res)
+	}
+
+	// The process holds the outputs with their SchemaDefRequirement types resolved.
+	values := cwl.Values{}
+	for _, out := range p.proc.tool.Outputs {
+		val, err := toValue(obj[out.ID])
+		if err != nil {
+			return nil, errf(`loading value for "%s": %s`, out.ID, err)
+		}
+
+		v, err := p.proc.bindOutput(nil, out.Type, nil, nil, val)
+		if err != nil {
+			return nil, errf(`failed to bind value for "%s": %s`, out.ID, err)
+		}
+		values[out.ID] = v
+	}
+	return values, nil
+}
+
+// toValue converts a value returned by expression evaluation
+// into the types produced by the input loader, converting objects
+// with a "File" or "Directory" class into cwl.File and cwl.Directory.
+func toValue(v interface{}) (cwl.Value, error) {
+	switch z := v.(type) {
+	case []interface{}:
+		out := make([]cwl.Value, len(z))
+		for i, x := range z {
+			y, err := toValue(x)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = y
+		}
+		return out, nil
+
+	case map[string]interface{}:
+		class, _ := z["class"].(string)
+		switch strings.ToLower(class) {
+		case "file", "directory":
+			// Use the document loader, which knows how to load File
+			// and Directory objects, by loading the object as a field
+			// of an input object.
+			b, err := json.Marshal(map[string]interface{}{"v": z})
+			if err != nil {
+				return nil, err
+			}
+			vals, err := cwl.LoadValuesBytes(b)
+			if err != nil {
+				return nil, err
+			}
+			return vals["v"], nil
+		}
+
+		out := map[string]cwl.Value{}
+		for k, x := range z {
+			y, err := toValue(x)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = y
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// toValues converts a decoded JSON object, such as a previously
+// marshaled output object, into an object of loader types via toValue.
+func toValues(obj map[string]interface{}) (cwl.Values, error) {
+	vals := cwl.Values{}
+	for k, v := range obj {
+		x, err := toValue(v)
+		if err != nil {
+			return nil, errf(`loading value for "%s": %s`, k, err)
+		}
+		vals[k] = x
+	}
+	return vals, nil
+}
@@ -0,0 +1,67 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+func TestExpressionToolOutputs(t *testing.T) {
+	doc := loadDoc(t, `
+class: ExpressionTool
+cwlVersion: v1.0
+requirements:
+  - class: InlineJavascriptRequirement
+    expressionLib:
+      - "function double(x) { return x * 2; }"
+inputs:
+  n: int
+outputs:
+  out: int
+  file: File
+expression: |
+  ${
+    return {
+      "out": double(inputs.n),
+      "file": {"class": "File", "location": "/tmp/out.txt"}
+    };
+  }
+`)
+
+	proc, err := NewExpressionProcess(doc.(*cwl.ExpressionTool), cwl.Values{"n": 21}, Runtime{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := proc.Outputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["out"] != int32(42) {
+		t.Errorf("expected out 42, got %#v", out["out"])
+	}
+	if f, ok := out["file"].(cwl.File); !ok || f.Location != "/tmp/out.txt" {
+		t.Errorf("expected File output, got %#v", out["file"])
+	}
+}
+
+func TestExpressionToolTypeCheck(t *testing.T) {
+	doc := loadDoc(t, `
+class: ExpressionTool
+cwlVersion: v1.0
+requirements:
+  - class: InlineJavascriptRequirement
+inputs: []
+outputs:
+  out: File
+expression: "$({'out': 'not a file'})"
+`)
+
+	proc, err := NewExpressionProcess(doc.(*cwl.ExpressionTool), cwl.Values{}, Runtime{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := proc.Outputs(); err == nil {
+		t.Error("expected type check error")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/lijiang2014/cwl"
 	"github.com/lijiang2014/cwl/expr"
+	"github.com/spf13/cast"
 	"path/filepath"
 	"strings"
 )
@@ -19,7 +20,13 @@ type Filesystem interface {
 	Create(path, contents string) (cwl.File, error)
 	Info(loc string) (cwl.File, error)
 	Contents(loc string) (string, error)
-	Glob(pattern string) ([]cwl.File, error)
+	// Glob returns the files and directories matching the pattern.
+	Glob(pattern string) ([]cwl.FileDir, error)
+	// DirInfo returns the location and path of a directory, without a listing.
+	DirInfo(loc string) (cwl.Directory, error)
+	// List returns the files and directories directly inside a directory.
+	// Only the location and path of each item need to be set.
+	List(loc string) ([]cwl.FileDir, error)
 }
 
 const MaxContentsBytes = 64 * units.Kilobyte
@@ -111,7 +118,148 @@ func (process *Process) resolveFile(f cwl.File, loadContents bool) (cwl.File, er
 	return f, nil
 }
 
-func (process *Process) resolveSecondaryFiles(file cwl.File, x cwl.Expression) error {
+// resolveDir uses the filesystem to fill in the location and path of a Directory,
+// and loads its listing as described by `listing`. The listing of a directory
+// literal, which has no location, is kept and resolved.
+func (process *Process) resolveDir(d cwl.Directory, listing cwl.LoadListing) (cwl.Directory, error) {
+	var x cwl.Directory
+
+	// Same special case as for File, see resolveFile.
+	if d.Location == "" && d.Path != "" {
+		d.Location = d.Path
+		d.Path = ""
+	}
+
+	if d.Location == "" {
+		// cwl spec:
+		// "If the location field is not provided, the listing field must be provided.
+		// The implementation must then create the directory."
+		items, err := process.resolveListing(d.Listing, listing)
+		if err != nil {
+			return x, err
+		}
+		d.Listing = items
+		return d, nil
+	}
+
+	x, err := process.fs.DirInfo(d.Location)
+	if err != nil {
+		return x, errf("getting directory info for %q: %s", d.Location, err)
+	}
+
+	d.Location = "file://" + x.Location
+	d.Path = x.Path
+	if d.Basename == "" {
+		d.Basename = filepath.Base(d.Path)
+	}
+
+	// cwl spec:
+	// "no_listing: Do not load the directory listing.
+	// shallow_listing: Only load the top level listing, do not recurse into subdirectories.
+	// deep_listing: Load the directory listing and recursively load all subdirectories as well."
+	d.Listing = nil
+	if listing == cwl.ShallowListing || listing == cwl.DeepListing {
+		items, err := process.fs.List(x.Location)
+		if err != nil {
+			return x, errf("listing directory %q: %s", x.Location, err)
+		}
+
+		next := listing
+		if listing == cwl.ShallowListing {
+			next = cwl.NoListing
+		}
+		d.Listing, err = process.resolveListing(items, next)
+		if err != nil {
+			return x, err
+		}
+	}
+	return d, nil
+}
+
+// resolveListing resolves the files and directories of a Directory listing.
+// Subdirectories are listed as described by `listing`.
+func (process *Process) resolveListing(items []cwl.FileDir, listing cwl.LoadListing) ([]cwl.FileDir, error) {
+	var out []cwl.FileDir
+	for _, item := range items {
+		switch z := item.(type) {
+		case cwl.File:
+			f, err := process.resolveFile(z, false)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, f)
+		case *cwl.File:
+			f, err := process.resolveFile(*z, false)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, f)
+		case cwl.Directory:
+			d, err := process.resolveDir(z, listing)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, d)
+		case *cwl.Directory:
+			d, err := process.resolveDir(*z, listing)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// setDirPath sets the path of a directory, and the paths of the files
+// and directories in its listing, which are relative to the directory.
+func setDirPath(d cwl.Directory, path string) cwl.Directory {
+	d.Path = path
+	if d.Listing == nil {
+		return d
+	}
+
+	listing := make([]cwl.FileDir, len(d.Listing))
+	for i, item := range d.Listing {
+		switch z := item.(type) {
+		case cwl.File:
+			z.Path = filepath.Join(path, z.Basename)
+			z.Dirname = path
+			listing[i] = z
+		case cwl.Directory:
+			listing[i] = setDirPath(z, filepath.Join(path, z.Basename))
+		default:
+			listing[i] = item
+		}
+	}
+	d.Listing = listing
+	return d
+}
+
+// loadListing returns how much of a Directory listing to load, given the
+// loadListing field of an input or output binding, which may be empty.
+func (process *Process) loadListing(l cwl.LoadListing) cwl.LoadListing {
+	if l != "" {
+		return l
+	}
+	if r, ok := process.tool.RequiresLoadListing(); ok && r.LoadListing != "" {
+		return r.LoadListing
+	}
+	// cwl v1.0 always loads the full listing,
+	// later versions default to no listing.
+	switch process.tool.CWLVersion {
+	case "", "v1.0":
+		return cwl.DeepListing
+	}
+	return cwl.NoListing
+}
+
+// resolveSecondaryFiles resolves a secondaryFiles item of a File,
+// and returns the File with the secondary file added.
+// A missing secondary file is an error only if it's required,
+// which by default is true for inputs and false for outputs.
+func (process *Process) resolveSecondaryFiles(file cwl.File, sf cwl.SecondaryFile, required bool) (cwl.File, error) {
+	x := sf.Pattern
 
 	// cwl spec:
 	// "If the value is an expression, the value of self in the expression
@@ -153,11 +301,24 @@ func (process *Process) resolveSecondaryFiles(file cwl.File, x cwl.Expression) e
 	// TODO does LoadContents apply to secondary files? not in the spec
 	f, err := process.resolveFile(sec, false)
 	if err != nil {
-		return err
+		if sf.Required != "" {
+			v, rerr := process.eval(sf.Required, file)
+			if rerr != nil {
+				return file, wrap(rerr, "evaluating secondaryFiles required")
+			}
+			required, rerr = cast.ToBoolE(v)
+			if rerr != nil {
+				return file, errf("secondaryFiles required must evaluate to a boolean, got %v", v)
+			}
+		}
+		if !required {
+			return file, nil
+		}
+		return file, err
 	}
 
 	file.SecondaryFiles = append(file.SecondaryFiles, f)
-	return nil
+	return file, nil
 }
 
 // splitname splits a file name into root and extension,
@@ -23,8 +23,8 @@ func NewLocal(workdir string) *Local {
 	return &Local{workdir, false}
 }
 
-func (l *Local) Glob(pattern string) ([]cwl.File, error) {
-	var out []cwl.File
+func (l *Local) Glob(pattern string) ([]cwl.FileDir, error) {
+	var out []cwl.FileDir
 
 	pattern = filepath.Join(l.workdir, pattern)
 
@@ -34,7 +34,21 @@ func (l *Local) Glob(pattern string) ([]cwl.File, error) {
 	}
 
 	for _, match := range matches {
+		st, err := os.Stat(match)
+		if err != nil {
+			return nil, errf("%s: %s", err, match)
+		}
 		match, _ := filepath.Rel(l.workdir, match)
+
+		if st.IsDir() {
+			d, err := l.DirInfo(match)
+			if err != nil {
+				return nil, errf("%s: %s", err, match)
+			}
+			out = append(out, d)
+			continue
+		}
+
 		f, err := l.Info(match)
 		if err != nil {
 			return nil, errf("%s: %s", err, match)
@@ -44,6 +58,58 @@ func (l *Local) Glob(pattern string) ([]cwl.File, error) {
 	return out, nil
 }
 
+func (l *Local) DirInfo(loc string) (cwl.Directory, error) {
+	var x cwl.Directory
+	loc = trimScheme(loc)
+	if !filepath.IsAbs(loc) {
+		loc = filepath.Join(l.workdir, loc)
+	}
+
+	st, err := os.Stat(loc)
+	if os.IsNotExist(err) {
+		return x, process.ErrFileNotFound
+	}
+	if err != nil {
+		return x, err
+	}
+	if !st.IsDir() {
+		return x, errf("not a directory: %s", loc)
+	}
+
+	abs, err := filepath.Abs(loc)
+	if err != nil {
+		return x, errf("getting absolute path for %s: %s", loc, err)
+	}
+	return cwl.Directory{
+		Location: abs,
+		Path:     abs,
+		Basename: filepath.Base(abs),
+	}, nil
+}
+
+func (l *Local) List(loc string) ([]cwl.FileDir, error) {
+	d, err := l.DirInfo(loc)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := ioutil.ReadDir(d.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []cwl.FileDir
+	for _, c := range children {
+		p := filepath.Join(d.Path, c.Name())
+		if c.IsDir() {
+			out = append(out, cwl.Directory{Location: p, Path: p})
+		} else {
+			out = append(out, cwl.File{Location: p, Path: p})
+		}
+	}
+	return out, nil
+}
+
 func (l *Local) Create(path, contents string) (cwl.File, error) {
   var x cwl.File
 	if path == "" {
@@ -79,6 +145,7 @@ func (l *Local) Create(path, contents string) (cwl.File, error) {
 
 func (l *Local) Info(loc string) (cwl.File, error) {
   var x cwl.File
+	loc = trimScheme(loc)
 	if !filepath.IsAbs(loc) {
 		loc = filepath.Join(l.workdir, loc)
 	}
@@ -91,7 +158,7 @@ func (l *Local) Info(loc string) (cwl.File, error) {
 		return x, err
 	}
 
-	// TODO make this work with directories
+	// Directories are handled by DirInfo.
 	if st.IsDir() {
 		return x, errf("can't call Info() on a directory: %s", loc)
 	}
@@ -119,6 +186,7 @@ func (l *Local) Info(loc string) (cwl.File, error) {
 }
 
 func (l *Local) Contents(loc string) (string, error) {
+	loc = trimScheme(loc)
 	if !filepath.IsAbs(loc) {
 		loc = filepath.Join(l.workdir, loc)
 	}
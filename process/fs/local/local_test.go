@@ -0,0 +1,165 @@
+package local_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/process"
+	"github.com/lijiang2014/cwl/process/fs/local"
+)
+
+// tempTree creates a directory "indir" holding a.txt and sub/b.txt.
+func tempTree(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cwl-local-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"indir/a.txt", "indir/sub/b.txt"} {
+		p = filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const dirTool = `
+class: CommandLineTool
+cwlVersion: v1.0
+baseCommand: ls
+inputs:
+  indir:
+    type: Directory
+    inputBinding: {}
+  listed:
+    type: Directory
+    loadListing: shallow_listing
+outputs:
+  out:
+    type: Directory
+    outputBinding:
+      glob: indir
+`
+
+// names returns the basenames of a directory listing, recursively.
+func names(d cwl.Directory) []string {
+	var out []string
+	for _, item := range d.Listing {
+		switch z := item.(type) {
+		case cwl.File:
+			out = append(out, z.Basename)
+		case cwl.Directory:
+			out = append(out, z.Basename+"/")
+			for _, n := range names(z) {
+				out = append(out, z.Basename+"/"+n)
+			}
+		}
+	}
+	return out
+}
+
+func TestDirectory(t *testing.T) {
+	dir := tempTree(t)
+	defer os.RemoveAll(dir)
+
+	doc, err := cwl.LoadDocumentBytes([]byte(dirTool), ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := local.NewLocal(dir)
+	proc, err := process.NewProcess(doc.(*cwl.Tool), cwl.Values{
+		"indir":  cwl.Directory{Location: "indir"},
+		"listed": cwl.Directory{Location: "indir"},
+	}, process.Runtime{}, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bindings := proc.InputBindings()
+	indir := bindings[0].Value.(cwl.Directory)
+	if indir.Location != "file://"+filepath.Join(dir, "indir") {
+		t.Errorf("unexpected location %q", indir.Location)
+	}
+	// cwl v1.0 loads the full listing.
+	expect := "[a.txt sub/ sub/b.txt]"
+	if got := fmtNames(names(indir)); got != expect {
+		t.Errorf("expected listing %s, got %s", expect, got)
+	}
+	// The listing is staged with the directory.
+	b := indir.Listing[1].(cwl.Directory).Listing[0].(cwl.File)
+	if b.Path != filepath.Join(indir.Path, "sub/b.txt") {
+		t.Errorf("unexpected path %q in listing of %q", b.Path, indir.Path)
+	}
+
+	listed := bindings[1].Value.(cwl.Directory)
+	if got := fmtNames(names(listed)); got != "[a.txt sub/]" {
+		t.Errorf("expected a shallow listing, got %s", got)
+	}
+
+	out, err := proc.Outputs(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, ok := out["out"].(cwl.Directory)
+	if !ok {
+		t.Fatalf("expected a Directory output, got %#v", out["out"])
+	}
+	if got := fmtNames(names(d)); got != expect {
+		t.Errorf("expected output listing %s, got %s", expect, got)
+	}
+}
+
+func fmtNames(n []string) string {
+	s := "["
+	for i, x := range n {
+		if i > 0 {
+			s += " "
+		}
+		s += x
+	}
+	return s + "]"
+}
+
+func TestLoadContents(t *testing.T) {
+	dir := tempTree(t)
+	defer os.RemoveAll(dir)
+
+	doc, err := cwl.LoadDocumentBytes([]byte(`
+class: CommandLineTool
+cwlVersion: v1.0
+baseCommand: cat
+inputs:
+  in:
+    type: File
+    inputBinding:
+      loadContents: true
+outputs: []
+`), ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Relative locations are resolved in the filesystem's directory.
+	proc, err := process.NewProcess(doc.(*cwl.Tool), cwl.Values{
+		"in": cwl.File{Location: "indir/a.txt"},
+	}, process.Runtime{}, local.NewLocal(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := proc.InputBindings()[0].Value.(cwl.File)
+	path := filepath.Join(dir, "indir/a.txt")
+	if in.Location != "file://"+path || in.Basename != "a.txt" {
+		t.Errorf("unexpected file %#v", in)
+	}
+	if in.Contents != path {
+		t.Errorf("expected contents %q, got %q", path, in.Contents)
+	}
+}
@@ -2,9 +2,16 @@ package local
 
 import (
 	"fmt"
+	"strings"
 )
 
 // errf makes fmt.Errorf shorter
 func errf(msg string, args ...interface{}) error {
 	return fmt.Errorf(msg, args...)
 }
+
+// trimScheme strips the "file://" scheme from a location, since locations
+// of resolved files are URIs but the local filesystem deals in paths.
+func trimScheme(loc string) string {
+	return strings.TrimPrefix(loc, "file://")
+}
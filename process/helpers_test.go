@@ -0,0 +1,157 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lijiang2014/cwl"
+)
+
+// nopExecutor doesn't run anything. Tools used with it must
+// compute their outputs with outputEval.
+type nopExecutor struct{}
+
+func (nopExecutor) Execute(proc *Process) (Filesystem, error) {
+	return nil, nil
+}
+
+const incTool = `
+class: CommandLineTool
+cwlVersion: v1.0
+baseCommand: "true"
+inputs:
+  n: int
+outputs:
+  out:
+    type: int
+    outputBinding:
+      outputEval: $(inputs.n + 1)
+`
+
+func loadDoc(t *testing.T, src string) cwl.Document {
+	doc, err := cwl.LoadDocumentBytes([]byte(src), ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// indent indents a document so it can be embedded as a step's "run" field.
+func indent(src string) string {
+	return strings.Replace(src, "\n", "\n      ", -1)
+}
+
+// checksumFS is a Filesystem of files identified only by their checksum.
+type checksumFS map[string]string
+
+func (fs checksumFS) Create(path, contents string) (cwl.File, error) {
+	return cwl.File{}, errf("not supported")
+}
+
+func (fs checksumFS) Info(loc string) (cwl.File, error) {
+	loc = strings.TrimPrefix(loc, "file://")
+	sum, ok := fs[loc]
+	if !ok {
+		return cwl.File{}, ErrFileNotFound
+	}
+	return cwl.File{Location: loc, Path: loc, Checksum: sum}, nil
+}
+
+func (fs checksumFS) Contents(loc string) (string, error) {
+	return "", errf("not supported")
+}
+
+func (fs checksumFS) Glob(pattern string) ([]cwl.FileDir, error) {
+	return nil, nil
+}
+
+func (fs checksumFS) DirInfo(loc string) (cwl.Directory, error) {
+	return cwl.Directory{}, errf("not supported")
+}
+
+func (fs checksumFS) List(loc string) ([]cwl.FileDir, error) {
+	return nil, errf("not supported")
+}
+
+// countExecutor counts executed processes, and fails those
+// whose input "n" equals fail.
+type countExecutor struct {
+	runs int32
+	fail int32
+}
+
+func (e *countExecutor) Execute(proc *Process) (Filesystem, error) {
+	atomic.AddInt32(&e.runs, 1)
+	for _, b := range proc.InputBindings() {
+		if b.name == "n" && b.Value == e.fail {
+			return nil, fmt.Errorf("job failed")
+		}
+	}
+	return nil, nil
+}
+
+// exitExecutor fails with the next exit code in codes on each run.
+type exitExecutor struct {
+	codes []int
+	runs  int
+}
+
+func (e *exitExecutor) Execute(proc *Process) (Filesystem, error) {
+	code := e.codes[e.runs]
+	e.runs++
+	return nil, proc.ExitError(code)
+}
+
+// sessionExecutor reports jobs as running on "node1", and records
+// the endpoints open while the command runs.
+type sessionExecutor struct {
+	table  *EndpointTable
+	during []Endpoint
+	code   int
+}
+
+func (e *sessionExecutor) Prepare(job *Job) error { return nil }
+func (e *sessionExecutor) Stage(job *Job) error   { return nil }
+
+func (e *sessionExecutor) Run(job *Job) (int, error) {
+	job.Running("node1")
+	job.Running("node2")
+	e.during = e.table.Endpoints()
+	return e.code, nil
+}
+
+func (e *sessionExecutor) Outputs(job *Job) (Filesystem, error) {
+	return nil, nil
+}
+
+// slowExecutor fails the processes whose input "n" equals fail at once,
+// and completes the others after a delay.
+type slowExecutor struct {
+	fail  int32
+	delay time.Duration
+	done  int32
+}
+
+func (e *slowExecutor) Execute(proc *Process) (Filesystem, error) {
+	for _, b := range proc.InputBindings() {
+		if b.name == "n" && b.Value == e.fail {
+			return nil, fmt.Errorf("job failed")
+		}
+	}
+	time.Sleep(e.delay)
+	atomic.AddInt32(&e.done, 1)
+	return nil, nil
+}
+
+// envExecutor records the environment of each executed process.
+type envExecutor struct {
+	env chan map[string]string
+}
+
+func (e envExecutor) Execute(proc *Process) (Filesystem, error) {
+	e.env <- proc.Env()
+	return nil, nil
+}
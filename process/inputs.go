@@ -4,6 +4,7 @@ import (
 	"github.com/lijiang2014/cwl"
 	"github.com/spf13/cast"
 	"path/filepath"
+	"strings"
 )
 
 /*** CWL input binding code ***/
@@ -32,6 +33,11 @@ func (process *Process) InputBindings() []*Binding {
 	return bindings
 }
 
+// Nested returns the bindings of array items or record fields.
+func (b *Binding) Nested() []*Binding {
+	return b.nested
+}
+
 // bindInput binds an input descriptor to a concrete value.
 //
 // bindInput is called recursively for types which have subtypes,
@@ -46,7 +52,7 @@ func (process *Process) bindInput(
 	name string,
 	types []cwl.InputType,
 	clb *cwl.CommandLineBinding,
-	secondaryFiles []cwl.Expression,
+	secondaryFiles []cwl.SecondaryFile,
 	val interface{},
 	key sortKey,
 ) ([]*Binding, error) {
@@ -64,6 +70,10 @@ func (process *Process) bindInput(
 		return nil, errf("missing value")
 	}
 
+	// lastErr describes why the last candidate type didn't match,
+	// which is more helpful than "missing value" when no type matches.
+	var lastErr error
+
 Loop:
 
 	// An input descriptor describes multiple allowed types.
@@ -108,33 +118,40 @@ Loop:
 				continue Loop
 			}
 
-			var out []*Binding
-
-			for i, field := range z.Fields {
-				val, ok := vals[field.Name]
-				// TODO lower case?
-				if !ok {
+			// The value only matches this record type if every field of the value
+			// is a field of the record, which makes a union of records exclusive.
+			fields := map[string]bool{}
+			for _, field := range z.Fields {
+				fields[fieldName(field.Name)] = true
+			}
+			for k := range vals {
+				if !fields[k] {
+					lastErr = errf("unknown record field %q", k)
 					continue Loop
 				}
+			}
 
-				subkey := append(key, sortKey{getPos(field.InputBinding), i}...)
-				b, err := process.bindInput(field.Name, field.Type, field.InputBinding, nil, val, subkey)
+			// Fields are sorted by their own position, either within the record
+			// or, if the record has no input binding, among the tool's inputs.
+			var nested []*Binding
+			rec := map[string]cwl.Value{}
+			for _, field := range z.Fields {
+				fname := fieldName(field.Name)
+				subkey := sortKey{getPos(field.InputBinding)}
+				b, err := process.bindInput(fname, field.Type, field.InputBinding, nil, vals[fname], subkey)
 				if err != nil {
-					return nil, err
-				}
-				if b == nil {
+					// e.g. a missing field, in which case another type may still match.
+					lastErr = errf("record field %q: %s", fname, err)
 					continue Loop
 				}
-				out = append(out, b...)
+				nested = append(nested, b...)
+				// Use the bound value, e.g. a resolved File, in the record value.
+				rec[fname] = b[0].Value
 			}
 
-			if out != nil {
-				nested := make([]*Binding, len(out))
-				copy(nested, out)
-				b := &Binding{clb, z, val, key, nested, name}
-				out = append(out, b)
-				return out, nil
-			}
+			return []*Binding{
+				{clb, z, rec, key, nested, name},
+			}, nil
 
 		case cwl.Any:
 			return []*Binding{
@@ -196,6 +213,26 @@ Loop:
 				{clb, z, v, key, nil, name},
 			}, nil
 
+		case cwl.InputEnum:
+			v, ok := val.(string)
+			if !ok {
+				continue Loop
+			}
+			sym, ok := enumSymbol(z.Symbols, v)
+			if !ok {
+				lastErr = errf("%q is not one of the enum symbols %v", v, z.Symbols)
+				continue Loop
+			}
+
+			// The enum's own binding applies if the parameter has none.
+			if clb == nil && z.InputBinding != nil {
+				clb = z.InputBinding
+				key = sortKey{getPos(clb)}
+			}
+			return []*Binding{
+				{clb, z, sym, key, nil, name},
+			}, nil
+
 		case cwl.FileType:
 			v, ok := val.(cwl.File)
 			if !ok {
@@ -210,8 +247,18 @@ Loop:
 			f.Path = filepath.Join(process.runtime.RootHost  ,"/inputs/" ,  f.Path)
 			
 			//f.Path = "/inputs/" + f.Path
-			for _, expr := range secondaryFiles {
-				process.resolveSecondaryFiles(f, expr)
+			for _, sf := range secondaryFiles {
+				f, err = process.resolveSecondaryFiles(f, sf, true)
+				if err != nil {
+					return nil, errf("resolving secondary files: %s", err)
+				}
+			}
+			// Secondary files are staged under /inputs, like the primary file.
+			for i, sec := range f.SecondaryFiles {
+				if s, ok := sec.(cwl.File); ok && s.Path != "" {
+					s.Path = filepath.Join(process.runtime.RootHost, "/inputs/", s.Path)
+					f.SecondaryFiles[i] = s
+				}
 			}
 
 			return []*Binding{
@@ -223,27 +270,48 @@ Loop:
 			if !ok {
 				continue Loop
 			}
-			// TODO resolve directory
+
+			d, err := process.resolveDir(v, process.listing)
+			if err != nil {
+				return nil, err
+			}
+			// Inputs are staged under /inputs, like files.
+			if d.Location != "" {
+				d = setDirPath(d, filepath.Join(process.runtime.RootHost, "/inputs/", d.Path))
+			}
 			return []*Binding{
-				{clb, z, v, key, nil, name},
+				{clb, z, d, key, nil, name},
 			}, nil
 		
 		case cwl.TypeRef:
-			if rsd , ok := process.tool.RequiresSchemaDef(); ok {
-				for _, rsdT :=range rsd.Types {
-					if rsdT.Name == z.Name[1:] {
-						//do something
-						// how to parse Value -> rsdT.Type ?
-						st := rsdT.Type
-						return []*Binding{
-							{ clb, z, st, key, nil, name },
-						},nil
-					}
-				}
-			}
-			continue Loop
+			// References are resolved by NewProcess.
+			return nil, errf("unresolved type %q", z.Name)
 		}
 	}
 
+	if lastErr != nil {
+		return nil, lastErr
+	}
 	return nil, errf("missing value")
 }
+
+// enumSymbol finds the value in a list of enum symbols, which may be namespaced
+// by document preprocessing, e.g. "#enum/symbol". The symbol is returned without
+// its namespace.
+func enumSymbol(symbols []string, val string) (string, bool) {
+	for _, s := range symbols {
+		if s == val || fieldName(s) == fieldName(val) {
+			return fieldName(s), true
+		}
+	}
+	return "", false
+}
+
+// fieldName returns the name of a record field or enum symbol, without the namespace
+// added by document preprocessing, e.g. "#rec/field" becomes "field".
+func fieldName(name string) string {
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		return name[i+1:]
+	}
+	return strings.TrimPrefix(name, "#")
+}
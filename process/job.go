@@ -0,0 +1,252 @@
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/rs/xid"
+)
+
+// Job describes how to run the command of a bound Process:
+// the command line and its environment, and the files staged for it.
+// Paths are in the filesystem seen by the command, e.g. inside a container.
+type Job struct {
+	ID      string
+	Command []string
+	Env     map[string]string
+	// Image is the docker image required by the tool, if any.
+	Image string
+	// Workdir is the working directory of the command, where its
+	// outputs are collected. Tmpdir is its temporary directory.
+	Workdir string
+	Tmpdir  string
+	// Stdin is the path of the file piped to the command's standard input.
+	// Stdout and Stderr are the paths of the files which capture the
+	// command's output. Each may be empty.
+	Stdin  string
+	Stdout string
+	Stderr string
+	// Inputs are the files and directories staged for the command.
+	Inputs []JobInput
+	// WorkDir is the InitialWorkDirRequirement listing, see StageWorkDir.
+	WorkDir   []WorkDirEntry
+	Resources Resources
+	// Scheduler describes how a batch scheduler should run the job.
+	Scheduler Scheduler
+	// Kubernetes describes the Kubernetes application of the job, if any.
+	Kubernetes *Kubernetes
+	// Session describes the endpoint of a session job, if any.
+	Session *Session
+	// HostDir is a host directory set by Executor.Prepare,
+	// e.g. holding the job's working directory.
+	HostDir string
+
+	// running is called by Running, see ExecuteSession.
+	running func(host string)
+}
+
+// JobInput is a file or directory staged at Path for the command.
+type JobInput struct {
+	Location  string
+	Path      string
+	Directory bool
+	// Writable inputs may be modified by the command,
+	// so they must be copies of the originals.
+	Writable bool
+}
+
+// NewJob describes the job running the command of a bound process.
+func NewJob(proc *Process) (*Job, error) {
+	cmd, err := proc.Command()
+	if err != nil {
+		return nil, err
+	}
+
+	rt := proc.runtime
+	job := &Job{
+		ID:         xid.New().String(),
+		Command:    cmd,
+		Env:        proc.Env(),
+		Workdir:    rt.Outdir,
+		Tmpdir:     rt.Tmpdir,
+		Stdin:      proc.Stdin(),
+		WorkDir:    proc.WorkDir(),
+		Resources:  proc.Resources(),
+		Scheduler:  proc.Scheduler(),
+		Kubernetes: proc.Kubernetes(),
+		Session:    proc.Session(),
+	}
+	if job.Tmpdir == "" {
+		job.Tmpdir = "/tmp"
+	}
+	if d, ok := proc.tool.RequiresDocker(); ok {
+		job.Image = d.Pull
+		if job.Image == "" {
+			job.Image = d.ImageID
+		}
+		if d.OutputDirectory != "" {
+			job.Workdir = d.OutputDirectory
+		}
+	}
+	if proc.Stdout() != "" {
+		job.Stdout = filepath.Join(job.Workdir, proc.Stdout())
+	}
+	if proc.Stderr() != "" {
+		job.Stderr = filepath.Join(job.Workdir, proc.Stderr())
+	}
+
+	// cwl spec: "HOME must be set to the designated output directory",
+	// "TMPDIR must be set to the designated temporary directory".
+	job.Env["HOME"] = job.Workdir
+	job.Env["TMPDIR"] = job.Tmpdir
+
+	// Inputs listed by InitialWorkDirRequirement are staged with the listing.
+	staged := map[string]bool{}
+	for _, e := range job.WorkDir {
+		staged[e.Path] = true
+	}
+	add := func(in JobInput) {
+		if in.Location != "" && !staged[in.Path] {
+			staged[in.Path] = true
+			job.Inputs = append(job.Inputs, in)
+		}
+	}
+	// Files may be nested in the bindings of array items and record fields.
+	var walk func(bindings []*Binding)
+	walk = func(bindings []*Binding) {
+		for _, b := range bindings {
+			switch z := b.Value.(type) {
+			case cwl.File:
+				for _, f := range flattenFiles(z) {
+					add(JobInput{Location: f.Location, Path: f.Path})
+				}
+			case cwl.Directory:
+				// Directories are staged as a whole, including their listing.
+				add(JobInput{Location: z.Location, Path: z.Path, Directory: true})
+			}
+			walk(b.nested)
+		}
+	}
+	walk(proc.bindings)
+
+	return job, nil
+}
+
+// flattenFiles returns a file followed by its secondary files, recursively.
+func flattenFiles(file cwl.File) []cwl.File {
+	files := []cwl.File{file}
+	for _, fd := range file.SecondaryFiles {
+		switch f := fd.(type) {
+		case cwl.File:
+			files = append(files, flattenFiles(f)...)
+		case *cwl.File:
+			files = append(files, flattenFiles(*f)...)
+		}
+	}
+	return files
+}
+
+// HostPath returns the host path of a local location,
+// i.e. an absolute path or a "file://" URL.
+func HostPath(loc string) (string, error) {
+	p := strings.TrimPrefix(loc, "file://")
+	if !filepath.IsAbs(p) {
+		return "", errf("%q is not a local file", loc)
+	}
+	return p, nil
+}
+
+// StageWorkDir prepares the InitialWorkDirRequirement listing of a job.
+// Files created by the listing, writable entries and everything inside them
+// are prepared in the host directory `dir`, at their path under `dir`,
+// so that the command never modifies the originals. An executor running
+// commands on the host may pass "/" to prepare entries in place.
+//
+// The returned inputs stage the listing: read-only entries of existing
+// files at their location, and the prepared entries.
+func StageWorkDir(entries []WorkDirEntry, dir string) ([]JobInput, error) {
+	var inputs []JobInput
+	// paths of prepared entries in the job's working directory.
+	var prepared []string
+
+	for _, e := range entries {
+		inPrepared := false
+		for _, p := range prepared {
+			if strings.HasPrefix(e.Path, p+"/") {
+				inPrepared = true
+			}
+		}
+
+		if !inPrepared && e.Location != "" && !e.Writable {
+			inputs = append(inputs, JobInput{Location: e.Location, Path: e.Path, Directory: e.Directory})
+			continue
+		}
+
+		host := filepath.Join(dir, e.Path)
+		if err := os.MkdirAll(filepath.Dir(host), 0755); err != nil {
+			return nil, err
+		}
+
+		var err error
+		switch {
+		case e.Location != "":
+			var src string
+			src, err = HostPath(e.Location)
+			if err == nil {
+				err = copyPath(src, host)
+			}
+		case e.Directory:
+			err = os.MkdirAll(host, 0755)
+		default:
+			err = ioutil.WriteFile(host, []byte(e.Contents), 0644)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !inPrepared {
+			prepared = append(prepared, e.Path)
+			inputs = append(inputs, JobInput{
+				Location:  host,
+				Path:      e.Path,
+				Directory: e.Directory,
+				Writable:  true,
+			})
+		}
+	}
+	return inputs, nil
+}
+
+// copyPath recursively copies a local file or directory.
+func copyPath(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		b, err := ioutil.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(dst, b, info.Mode())
+	}
+
+	if err := os.MkdirAll(dst, info.Mode()); err != nil {
+		return err
+	}
+	children, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		err := copyPath(filepath.Join(src, c.Name()), filepath.Join(dst, c.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
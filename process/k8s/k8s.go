@@ -0,0 +1,291 @@
+// Package k8s renders the jobs of CommandLineTools as Kubernetes manifests.
+//
+// A batch application is rendered as a Job. A session application, whose
+// KubernetesRequirement lists deployments, is rendered as a Deployment
+// per deployment and a Service per deployment exposed by a proxy.
+package k8s
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+	"github.com/lijiang2014/cwl/process"
+)
+
+// Renderer renders jobs as Kubernetes manifests.
+type Renderer struct {
+	// Namespace of the rendered objects, if any.
+	Namespace string
+	// DefaultImage is used by jobs which don't require a docker image.
+	DefaultImage string
+}
+
+// Manifests returns the Kubernetes objects running the job.
+func (r *Renderer) Manifests(job *process.Job) ([]interface{}, error) {
+	name := "cwl-" + job.ID
+	pod, workdir, err := r.podSpec(job, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var objs []interface{}
+	if workdir != nil {
+		objs = append(objs, workdir)
+	}
+
+	if job.Kubernetes == nil || len(job.Kubernetes.Deployments) == 0 {
+		pod.RestartPolicy = "Never"
+		backoff := 0
+		objs = append(objs, &Job{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+			Metadata:   r.meta(name, name),
+			Spec: JobSpec{
+				BackoffLimit: &backoff,
+				Template: PodTemplate{
+					Metadata: ObjectMeta{Labels: labels(name)},
+					Spec:     pod,
+				},
+			},
+		})
+		return objs, nil
+	}
+
+	// Deployments and services are named after the job too, like the Job of
+	// a batch application, so that the objects of concurrent jobs, e.g. of
+	// a scattered step, don't collide.
+	for _, d := range job.Kubernetes.Deployments {
+		replicas := d.Replicas
+		dname := d.Name + "-" + job.ID
+		objs = append(objs, &Deployment{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Metadata:   r.meta(dname, name),
+			Spec: DeploymentSpec{
+				Replicas: &replicas,
+				Selector: LabelSelector{MatchLabels: labels(dname)},
+				Template: PodTemplate{
+					Metadata: ObjectMeta{Labels: labels(dname)},
+					Spec:     pod,
+				},
+			},
+		})
+	}
+
+	// Each exposed deployment gets a service with the ports of its proxies.
+	ports := map[string][]ServicePort{}
+	var exposed []string
+	for _, p := range job.Kubernetes.Proxies {
+		if _, ok := ports[p.Deployment]; !ok {
+			exposed = append(exposed, p.Deployment)
+		}
+		ports[p.Deployment] = append(ports[p.Deployment], ServicePort{
+			Name:       p.Name,
+			Port:       p.Port,
+			TargetPort: p.TargetPort,
+		})
+	}
+	for _, d := range exposed {
+		dname := d + "-" + job.ID
+		objs = append(objs, &Service{
+			APIVersion: "v1",
+			Kind:       "Service",
+			Metadata:   r.meta(dname, name),
+			Spec: ServiceSpec{
+				Selector: labels(dname),
+				Ports:    ports[d],
+			},
+		})
+	}
+	return objs, nil
+}
+
+// Render writes the manifests of the job as a YAML stream.
+func (r *Renderer) Render(w io.Writer, job *process.Job) error {
+	objs, err := r.Manifests(job)
+	if err != nil {
+		return err
+	}
+	for i, obj := range objs {
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		b, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Renderer) meta(name, job string) ObjectMeta {
+	return ObjectMeta{
+		Name:      name,
+		Namespace: r.Namespace,
+		Labels:    map[string]string{"app": name, "cwl-job": job},
+	}
+}
+
+func labels(name string) map[string]string {
+	return map[string]string{"app": name}
+}
+
+// podSpec returns the spec of the pods running the job's command,
+// and the config map holding the files created by the job's
+// InitialWorkDirRequirement listing, if any.
+func (r *Renderer) podSpec(job *process.Job, name string) (PodSpec, *ConfigMap, error) {
+	image := job.Image
+	if image == "" {
+		image = r.DefaultImage
+	}
+	if image == "" {
+		return PodSpec{}, nil, fmt.Errorf("no docker image")
+	}
+	if len(job.Command) == 0 {
+		return PodSpec{}, nil, fmt.Errorf("empty command")
+	}
+
+	c := Container{
+		Name:       "main",
+		Image:      image,
+		Command:    command(job),
+		WorkingDir: job.Workdir,
+		Resources:  resources(job.Resources),
+		VolumeMounts: []VolumeMount{
+			{Name: "workdir", MountPath: job.Workdir},
+			{Name: "tmpdir", MountPath: job.Tmpdir},
+		},
+	}
+	var env []string
+	for k := range job.Env {
+		env = append(env, k)
+	}
+	sort.Strings(env)
+	for _, k := range env {
+		c.Env = append(c.Env, EnvVar{Name: k, Value: job.Env[k]})
+	}
+
+	if job.Session != nil {
+		c.Ports = []ContainerPort{{ContainerPort: job.Session.Port}}
+	}
+
+	pod := PodSpec{
+		Volumes: []Volume{
+			{Name: "workdir", EmptyDir: &EmptyDirSource{}},
+			{Name: "tmpdir", EmptyDir: &EmptyDirSource{}},
+		},
+	}
+
+	// Inputs are host paths, mounted read-only at their paths.
+	for i, in := range job.Inputs {
+		src, err := process.HostPath(in.Location)
+		if err != nil {
+			return PodSpec{}, nil, err
+		}
+		vol := "input-" + strconv.Itoa(i)
+		pod.Volumes = append(pod.Volumes, Volume{Name: vol, HostPath: &HostPathSource{Path: src}})
+		c.VolumeMounts = append(c.VolumeMounts, VolumeMount{
+			Name: vol, MountPath: in.Path, ReadOnly: !in.Writable,
+		})
+	}
+
+	// Files created by the listing are keys of a config map, mounted at their
+	// paths in the working directory. Other entries are mounted like inputs.
+	var cm *ConfigMap
+	for i, e := range job.WorkDir {
+		switch {
+		case e.Location != "":
+			src, err := process.HostPath(e.Location)
+			if err != nil {
+				return PodSpec{}, nil, err
+			}
+			vol := "workdir-" + strconv.Itoa(i)
+			pod.Volumes = append(pod.Volumes, Volume{Name: vol, HostPath: &HostPathSource{Path: src}})
+			c.VolumeMounts = append(c.VolumeMounts, VolumeMount{
+				Name: vol, MountPath: e.Path, ReadOnly: !e.Writable,
+			})
+		case !e.Directory:
+			if cm == nil {
+				cm = &ConfigMap{
+					APIVersion: "v1",
+					Kind:       "ConfigMap",
+					Metadata:   r.meta(name+"-workdir", name),
+					Data:       map[string]string{},
+				}
+				pod.Volumes = append(pod.Volumes, Volume{
+					Name:      "listing",
+					ConfigMap: &ConfigMapSource{Name: cm.Metadata.Name},
+				})
+			}
+			key := "entry-" + strconv.Itoa(i)
+			cm.Data[key] = e.Contents
+			c.VolumeMounts = append(c.VolumeMounts, VolumeMount{
+				Name: "listing", MountPath: e.Path, SubPath: key,
+			})
+		}
+	}
+
+	pod.Containers = []Container{c}
+	return pod, cm, nil
+}
+
+// command returns the container command running the job's command,
+// which is wrapped by a shell if its standard streams are redirected.
+func command(job *process.Job) []string {
+	if job.Stdin == "" && job.Stdout == "" && job.Stderr == "" {
+		return job.Command
+	}
+	var cmd []string
+	for _, arg := range job.Command {
+		cmd = append(cmd, quote(arg))
+	}
+	if job.Stdin != "" {
+		cmd = append(cmd, "<", quote(job.Stdin))
+	}
+	if job.Stdout != "" {
+		cmd = append(cmd, ">", quote(job.Stdout))
+	}
+	if job.Stderr != "" {
+		cmd = append(cmd, "2>", quote(job.Stderr))
+	}
+	return []string{"/bin/sh", "-c", "exec " + strings.Join(cmd, " ")}
+}
+
+// quote quotes a string for the shell.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// resources returns the resource requests and limits of the containers.
+// The minimum resources are requested, and the maximum are the limits.
+func resources(res process.Resources) ResourceRequirements {
+	rr := ResourceRequirements{}
+	if res.CoresMin > 0 || res.RAMMin > 0 {
+		rr.Requests = map[string]string{}
+	}
+	if res.CoresMin > 0 {
+		rr.Requests["cpu"] = strconv.Itoa(res.CoresMin)
+	}
+	if res.RAMMin > 0 {
+		rr.Requests["memory"] = fmt.Sprintf("%dMi", res.RAMMin)
+	}
+	if res.CoresMax > 0 || res.RAMMax > 0 {
+		rr.Limits = map[string]string{}
+	}
+	if res.CoresMax > 0 {
+		rr.Limits["cpu"] = strconv.Itoa(res.CoresMax)
+	}
+	if res.RAMMax > 0 {
+		rr.Limits["memory"] = fmt.Sprintf("%dMi", res.RAMMax)
+	}
+	return rr
+}
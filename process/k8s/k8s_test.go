@@ -0,0 +1,238 @@
+package k8s
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-yaml/yaml"
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/process"
+)
+
+func newJob(t *testing.T, doc string, vals cwl.Values) (*process.Job, error) {
+	d, err := cwl.LoadDocumentBytes([]byte(doc), ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := process.Runtime{Outdir: "/cwl", Tmpdir: "/tmp"}
+	proc, err := process.NewProcess(d.(*cwl.Tool), vals, rt, nil)
+	if err != nil {
+		return nil, err
+	}
+	return process.NewJob(proc)
+}
+
+const batchTool = `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  DockerRequirement:
+    dockerPull: alpine:3
+  ResourceRequirement:
+    coresMin: 2
+    coresMax: 4
+    ramMin: 1024
+  InitialWorkDirRequirement:
+    listing:
+      - entryname: config.ini
+        entry: "n=$(inputs.n)"
+inputs:
+  n:
+    type: int
+    inputBinding:
+      prefix: -n
+outputs:
+  out:
+    type: stdout
+baseCommand: fit
+stdout: fit.log
+`
+
+func TestRenderJob(t *testing.T) {
+	job, err := newJob(t, batchTool, cwl.Values{"n": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := Renderer{Namespace: "apps"}
+	objs, err := r.Manifests(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected a config map and a job, got %d objects", len(objs))
+	}
+
+	cm := objs[0].(*ConfigMap)
+	if len(cm.Data) != 1 || cm.Data["entry-0"] != "n=10" {
+		t.Errorf("unexpected config map data: %v", cm.Data)
+	}
+
+	j := objs[1].(*Job)
+	if j.Metadata.Name != "cwl-"+job.ID || j.Metadata.Namespace != "apps" {
+		t.Errorf("unexpected metadata: %+v", j.Metadata)
+	}
+	pod := j.Spec.Template.Spec
+	if pod.RestartPolicy != "Never" {
+		t.Errorf("expected restart policy Never, got %q", pod.RestartPolicy)
+	}
+	c := pod.Containers[0]
+	if c.Image != "alpine:3" {
+		t.Errorf("unexpected image %q", c.Image)
+	}
+	expectCmd := []string{"/bin/sh", "-c", "exec 'fit' '-n' '10' > '/cwl/fit.log'"}
+	if strings.Join(c.Command, "\x00") != strings.Join(expectCmd, "\x00") {
+		t.Errorf("unexpected command %q", c.Command)
+	}
+	if c.Resources.Requests["cpu"] != "2" || c.Resources.Requests["memory"] != "1024Mi" {
+		t.Errorf("unexpected requests %v", c.Resources.Requests)
+	}
+	if c.Resources.Limits["cpu"] != "4" {
+		t.Errorf("unexpected limits %v", c.Resources.Limits)
+	}
+
+	found := false
+	for _, m := range c.VolumeMounts {
+		if m.Name == "listing" && m.MountPath == "/cwl/config.ini" && m.SubPath == "entry-0" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected config.ini to be mounted: %+v", c.VolumeMounts)
+	}
+
+	// The rendered stream is valid YAML.
+	var buf bytes.Buffer
+	if err := r.Render(&buf, job); err != nil {
+		t.Fatal(err)
+	}
+	docs := strings.Split(buf.String(), "---\n")
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %d:\n%s", len(docs), buf.String())
+	}
+	var out map[string]interface{}
+	if err := yaml.Unmarshal([]byte(docs[1]), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["kind"] != "Job" || out["apiVersion"] != "batch/v1" {
+		t.Errorf("unexpected document:\n%s", docs[1])
+	}
+}
+
+const sessionTool = `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  DockerRequirement:
+    dockerPull: tensorflow/tensorflow:latest-jupyter
+  KubernetesRequirement:
+    deployments:
+      - '$({name: inputs.name, replicas: 2})'
+    proxies:
+      - "8888"
+      - '$({name: "board", port: 80, targetPort: 6006})'
+inputs:
+  name: string
+outputs: []
+baseCommand: [jupyter, notebook]
+`
+
+func TestRenderSession(t *testing.T) {
+	job, err := newJob(t, sessionTool, cwl.Values{"name": "notebook"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := Renderer{}
+	objs, err := r.Manifests(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected a deployment and a service, got %d objects", len(objs))
+	}
+
+	name := "notebook-" + job.ID
+	d := objs[0].(*Deployment)
+	if d.Metadata.Name != name || *d.Spec.Replicas != 2 {
+		t.Errorf("unexpected deployment %+v", d)
+	}
+	if d.Spec.Selector.MatchLabels["app"] != name ||
+		d.Spec.Template.Metadata.Labels["app"] != name {
+		t.Errorf("unexpected deployment labels %+v", d.Spec)
+	}
+	if cmd := d.Spec.Template.Spec.Containers[0].Command; strings.Join(cmd, " ") != "jupyter notebook" {
+		t.Errorf("unexpected command %q", cmd)
+	}
+
+	s := objs[1].(*Service)
+	if s.Metadata.Name != name || s.Spec.Selector["app"] != name {
+		t.Errorf("unexpected service %+v", s)
+	}
+	expect := []ServicePort{
+		{Port: 8888, TargetPort: 8888},
+		{Name: "board", Port: 80, TargetPort: 6006},
+	}
+	if len(s.Spec.Ports) != 2 || s.Spec.Ports[0] != expect[0] || s.Spec.Ports[1] != expect[1] {
+		t.Errorf("unexpected ports %+v", s.Spec.Ports)
+	}
+}
+
+func TestRenderSessionJobs(t *testing.T) {
+	// Two jobs of the same tool, e.g. of a scattered step,
+	// get objects with different names and selectors.
+	names := map[string]bool{}
+	selectors := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		job, err := newJob(t, sessionTool, cwl.Values{"name": "notebook"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		objs, err := (&Renderer{}).Manifests(job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := objs[0].(*Deployment)
+		s := objs[1].(*Service)
+		names[d.Metadata.Name] = true
+		names[s.Metadata.Name] = true
+		selectors[s.Spec.Selector["app"]] = true
+	}
+	if len(names) != 2 || len(selectors) != 2 {
+		t.Errorf("expected the objects of each job to have their own names, got %v and selectors %v", names, selectors)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	_, err := newJob(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  KubernetesRequirement:
+    proxies: ["8080"]
+inputs: []
+outputs: []
+baseCommand: serve
+`, cwl.Values{})
+	if err == nil {
+		t.Error("expected an error for proxies without a deployment")
+	}
+
+	job, err := newJob(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+inputs: []
+outputs: []
+baseCommand: serve
+`, cwl.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&Renderer{}).Manifests(job); err == nil {
+		t.Error("expected an error for a job without an image")
+	}
+	if _, err := (&Renderer{DefaultImage: "alpine"}).Manifests(job); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,126 @@
+package k8s
+
+// The subset of the Kubernetes API objects rendered for jobs.
+// See https://kubernetes.io/docs/reference/kubernetes-api/
+
+type ObjectMeta struct {
+	Name      string            `yaml:"name,omitempty"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type Job struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   ObjectMeta `yaml:"metadata"`
+	Spec       JobSpec    `yaml:"spec"`
+}
+
+type JobSpec struct {
+	BackoffLimit *int        `yaml:"backoffLimit,omitempty"`
+	Template     PodTemplate `yaml:"template"`
+}
+
+type Deployment struct {
+	APIVersion string         `yaml:"apiVersion"`
+	Kind       string         `yaml:"kind"`
+	Metadata   ObjectMeta     `yaml:"metadata"`
+	Spec       DeploymentSpec `yaml:"spec"`
+}
+
+type DeploymentSpec struct {
+	Replicas *int          `yaml:"replicas,omitempty"`
+	Selector LabelSelector `yaml:"selector"`
+	Template PodTemplate   `yaml:"template"`
+}
+
+type LabelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels,omitempty"`
+}
+
+type Service struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   ObjectMeta  `yaml:"metadata"`
+	Spec       ServiceSpec `yaml:"spec"`
+}
+
+type ServiceSpec struct {
+	Selector map[string]string `yaml:"selector,omitempty"`
+	Ports    []ServicePort     `yaml:"ports,omitempty"`
+}
+
+type ServicePort struct {
+	Name       string `yaml:"name,omitempty"`
+	Port       int    `yaml:"port"`
+	TargetPort int    `yaml:"targetPort,omitempty"`
+}
+
+type ConfigMap struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   ObjectMeta        `yaml:"metadata"`
+	Data       map[string]string `yaml:"data,omitempty"`
+}
+
+type PodTemplate struct {
+	Metadata ObjectMeta `yaml:"metadata,omitempty"`
+	Spec     PodSpec    `yaml:"spec"`
+}
+
+type PodSpec struct {
+	RestartPolicy string      `yaml:"restartPolicy,omitempty"`
+	Containers    []Container `yaml:"containers"`
+	Volumes       []Volume    `yaml:"volumes,omitempty"`
+}
+
+type Container struct {
+	Name         string               `yaml:"name"`
+	Image        string               `yaml:"image"`
+	Command      []string             `yaml:"command,omitempty"`
+	WorkingDir   string               `yaml:"workingDir,omitempty"`
+	Env          []EnvVar             `yaml:"env,omitempty"`
+	Ports        []ContainerPort      `yaml:"ports,omitempty"`
+	Resources    ResourceRequirements `yaml:"resources,omitempty"`
+	VolumeMounts []VolumeMount        `yaml:"volumeMounts,omitempty"`
+}
+
+type ContainerPort struct {
+	ContainerPort int `yaml:"containerPort"`
+}
+
+type EnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// ResourceRequirements maps resource names, i.e. "cpu" and "memory",
+// to quantities.
+type ResourceRequirements struct {
+	Requests map[string]string `yaml:"requests,omitempty"`
+	Limits   map[string]string `yaml:"limits,omitempty"`
+}
+
+type VolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	SubPath   string `yaml:"subPath,omitempty"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty"`
+}
+
+type Volume struct {
+	Name      string           `yaml:"name"`
+	EmptyDir  *EmptyDirSource  `yaml:"emptyDir,omitempty"`
+	HostPath  *HostPathSource  `yaml:"hostPath,omitempty"`
+	ConfigMap *ConfigMapSource `yaml:"configMap,omitempty"`
+}
+
+type EmptyDirSource struct{}
+
+type HostPathSource struct {
+	Path string `yaml:"path"`
+}
+
+type ConfigMapSource struct {
+	Name string `yaml:"name"`
+}
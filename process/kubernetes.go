@@ -0,0 +1,123 @@
+package process
+
+import (
+	"regexp"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/spf13/cast"
+)
+
+// Kubernetes describes how a tool runs as a Kubernetes application,
+// from its KubernetesRequirement. A tool without deployments is a batch
+// application, run as a Job. A tool with deployments is a session
+// application: its command runs in long-lived deployments, whose ports
+// are exposed by services.
+type Kubernetes struct {
+	Deployments []KubernetesDeployment
+	Proxies     []KubernetesProxy
+}
+
+// KubernetesDeployment is a deployment running the tool's command.
+type KubernetesDeployment struct {
+	Name     string
+	Replicas int
+}
+
+// KubernetesProxy exposes a port of a deployment's containers.
+type KubernetesProxy struct {
+	// Name of the service port, if any.
+	Name string
+	// Port is the port of the service,
+	// TargetPort the port of the containers.
+	Port       int
+	TargetPort int
+	// Deployment is the name of the deployment exposed by the proxy.
+	// Defaults to the first deployment.
+	Deployment string
+}
+
+// dnsLabel matches names which are valid Kubernetes object names.
+var dnsLabel = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// evalKubernetes evaluates the expressions of a KubernetesRequirement.
+//
+// Each deployment expression evaluates to the name of a deployment,
+// or an object with "name" and "replicas" fields. Each proxy expression
+// evaluates to a port number, or an object with "name", "port", "targetPort"
+// and "deployment" fields.
+func (process *Process) evalKubernetes(req cwl.KubernetesRequirement) error {
+	k := &Kubernetes{}
+
+	for i, x := range req.Deployments {
+		val, err := process.eval(x, nil)
+		if err != nil {
+			return errf("failed to evaluate deployment %d: %s", i, err)
+		}
+		d := KubernetesDeployment{Replicas: 1}
+		switch z := val.(type) {
+		case string:
+			d.Name = z
+		case map[string]interface{}:
+			if d.Name, err = cast.ToStringE(z["name"]); err != nil {
+				return errf("deployment %d: name must be a string, got %v", i, z["name"])
+			}
+			if r, ok := z["replicas"]; ok {
+				if d.Replicas, err = cast.ToIntE(r); err != nil || d.Replicas < 0 {
+					return errf("deployment %d: replicas must be a positive integer, got %v", i, r)
+				}
+			}
+		default:
+			return errf("deployment %d must be a name or an object, got %v", i, val)
+		}
+		if !dnsLabel.MatchString(d.Name) {
+			return errf("deployment %d: invalid name %q", i, d.Name)
+		}
+		k.Deployments = append(k.Deployments, d)
+	}
+
+	for i, x := range req.Proxies {
+		val, err := process.eval(x, nil)
+		if err != nil {
+			return errf("failed to evaluate proxy %d: %s", i, err)
+		}
+		var p KubernetesProxy
+		if z, ok := val.(map[string]interface{}); ok {
+			p.Name = cast.ToString(z["name"])
+			p.Deployment = cast.ToString(z["deployment"])
+			if p.Port, err = cast.ToIntE(z["port"]); err != nil {
+				return errf("proxy %d: port must be an integer, got %v", i, z["port"])
+			}
+			if t, ok := z["targetPort"]; ok {
+				if p.TargetPort, err = cast.ToIntE(t); err != nil {
+					return errf("proxy %d: targetPort must be an integer, got %v", i, t)
+				}
+			}
+		} else if p.Port, err = cast.ToIntE(val); err != nil {
+			return errf("proxy %d must be a port or an object, got %v", i, val)
+		}
+		if p.Port <= 0 {
+			return errf("proxy %d: invalid port %d", i, p.Port)
+		}
+		if p.TargetPort == 0 {
+			p.TargetPort = p.Port
+		}
+
+		if len(k.Deployments) == 0 {
+			return errf("proxy %d: proxies require a deployment", i)
+		}
+		if p.Deployment == "" {
+			p.Deployment = k.Deployments[0].Name
+		}
+		found := false
+		for _, d := range k.Deployments {
+			found = found || d.Name == p.Deployment
+		}
+		if !found {
+			return errf("proxy %d: unknown deployment %q", i, p.Deployment)
+		}
+		k.Proxies = append(k.Proxies, p)
+	}
+
+	process.kubernetes = k
+	return nil
+}
@@ -41,7 +41,7 @@ func (process *Process) bindOutput(
 	fs Filesystem,
 	types []cwl.OutputType,
 	binding *cwl.CommandOutputBinding,
-	secondaryFiles []cwl.Expression,
+	secondaryFiles []cwl.SecondaryFile,
 	val interface{},
 ) (interface{}, error) {
 	var err error
@@ -53,7 +53,8 @@ func (process *Process) bindOutput(
 			return nil, errf("failed to evaluate glob expressions: %s", err)
 		}
 
-		files, err := process.matchFiles(fs, globs, binding.LoadContents)
+		listing := process.loadListing(binding.LoadListing)
+		files, err := process.matchFiles(fs, globs, binding.LoadContents, listing)
 		if err != nil {
 			return nil, errf("failed to match files: %s", err)
 		}
@@ -75,12 +76,22 @@ func (process *Process) bindOutput(
 		}
 	}
 
+	// cwl spec: the fields of a record output are bound by their own outputBinding,
+	// so a record doesn't need a value of its own.
+	if val == nil {
+		for _, t := range types {
+			if z, ok := t.(cwl.OutputRecord); ok {
+				return process.bindOutputRecord(fs, z, nil)
+			}
+		}
+	}
+
 	for _, t := range types {
 		switch t.(type) {
 		// TODO validate stdout/err can only be at root
 		//      validate that stdout/err doesn't occur more than once
 		case cwl.Stdout:
-			files, err := process.matchFiles(fs, []string{process.stdout}, false)
+			files, err := process.matchFiles(fs, []string{process.stdout}, false, cwl.NoListing)
 			if err != nil {
 				return nil, errf("failed to match files: %s", err)
 			}
@@ -93,7 +104,7 @@ func (process *Process) bindOutput(
 			return files[0], nil
 
 		case cwl.Stderr:
-			files, err := process.matchFiles(fs, []string{process.stderr}, false)
+			files, err := process.matchFiles(fs, []string{process.stderr}, false, cwl.NoListing)
 			if err != nil {
 				return nil, errf("failed to match files: %s", err)
 			}
@@ -115,6 +126,8 @@ func (process *Process) bindOutput(
 Loop:
 	for _, t := range types {
 		switch z := t.(type) {
+		case cwl.Any:
+			return val, nil
 		case cwl.Boolean:
 			v, err := cast.ToBoolE(val)
 			if err == nil {
@@ -145,15 +158,27 @@ Loop:
 			if err == nil {
 				return v, nil
 			}
+		case cwl.OutputEnum:
+			v, ok := val.(string)
+			if !ok {
+				continue Loop
+			}
+			if sym, ok := enumSymbol(z.Symbols, v); ok {
+				return sym, nil
+			}
 		case cwl.FileType:
 			switch y := val.(type) {
-			case []cwl.File:
+			case []cwl.FileDir:
 				if len(y) != 1 {
 					continue Loop
 				}
-				f := y[0]
-				for _, expr := range secondaryFiles {
-					err := process.resolveSecondaryFiles(f, expr)
+				f, ok := y[0].(cwl.File)
+				if !ok {
+					continue Loop
+				}
+				for _, sf := range secondaryFiles {
+					var err error
+					f, err = process.resolveSecondaryFiles(f, sf, false)
 					if err != nil {
 						return nil, errf("resolving secondary files: %s", err)
 					}
@@ -162,11 +187,42 @@ Loop:
 
 			case cwl.File:
 				return y, nil
+			case map[string]interface{}:
+				// e.g. a File returned by outputEval
+				if f, err := toValue(y); err == nil {
+					if f, ok := f.(cwl.File); ok {
+						return f, nil
+					}
+				}
+				continue Loop
 			default:
 				continue Loop
 			}
 		case cwl.DirectoryType:
-			// TODO
+			switch y := val.(type) {
+			case []cwl.FileDir:
+				if len(y) != 1 {
+					continue Loop
+				}
+				d, ok := y[0].(cwl.Directory)
+				if !ok {
+					continue Loop
+				}
+				return d, nil
+
+			case cwl.Directory:
+				return y, nil
+			case map[string]interface{}:
+				// e.g. a Directory returned by outputEval
+				if d, err := toValue(y); err == nil {
+					if d, ok := d.(cwl.Directory); ok {
+						return d, nil
+					}
+				}
+				continue Loop
+			default:
+				continue Loop
+			}
 		case cwl.OutputArray:
 			typ := reflect.TypeOf(val)
 			if typ.Kind() != reflect.Slice {
@@ -190,20 +246,49 @@ Loop:
 			return res, nil
 
 		case cwl.OutputRecord:
-			// TODO
-
+			switch val.(type) {
+			case map[string]interface{}, map[string]cwl.Value:
+				return process.bindOutputRecord(fs, z, val)
+			}
+			continue Loop
 		}
 	}
 
 	return nil, errf("no type could be matched")
 }
 
-// matchFiles executes the list of glob patterns, returning a list of matched files.
+// bindOutputRecord binds the fields of a record output. A field value is
+// taken from the record value, e.g. returned by outputEval, unless the field
+// has its own outputBinding.
+func (process *Process) bindOutputRecord(fs Filesystem, rec cwl.OutputRecord, val interface{}) (interface{}, error) {
+	out := map[string]interface{}{}
+	for _, field := range rec.Fields {
+		name := fieldName(field.Name)
+
+		var fval interface{}
+		switch z := val.(type) {
+		case map[string]interface{}:
+			fval = z[name]
+		case map[string]cwl.Value:
+			fval = z[name]
+		}
+
+		v, err := process.bindOutput(fs, field.Type, field.OutputBinding, nil, fval)
+		if err != nil {
+			return nil, errf("failed to bind record field %q: %s", name, err)
+		}
+		out[name] = v
+	}
+	return out, nil
+}
+
+// matchFiles executes the list of glob patterns, returning a list of matched
+// files and directories. The listing of directories is loaded as described by `listing`.
 // matchFiles must return a non-nil list on success, even if no files are matched.
-func (process *Process) matchFiles(fs Filesystem, globs []string, loadContents bool) ([]cwl.File, error) {
+func (process *Process) matchFiles(fs Filesystem, globs []string, loadContents bool, listing cwl.LoadListing) ([]cwl.FileDir, error) {
 	// it's important this slice isn't nil, because the outputEval field
 	// expects it to be non-null during expression evaluation.
-	files := []cwl.File{}
+	files := []cwl.FileDir{}
 
 	// resolve all the globs into file objects.
 	for _, pattern := range globs {
@@ -213,19 +298,28 @@ func (process *Process) matchFiles(fs Filesystem, globs []string, loadContents b
 		}
 
 		for _, m := range matches {
-			// TODO handle directories
-			v := cwl.File{
-				Location: m.Location,
-				Path:     m.Path,
-				Checksum: m.Checksum,
-				Size:     m.Size,
-			}
+			switch m := m.(type) {
+			case cwl.File:
+				v := cwl.File{
+					Location: m.Location,
+					Path:     m.Path,
+					Checksum: m.Checksum,
+					Size:     m.Size,
+				}
 
-			f, err := process.resolveFile(v, loadContents)
-			if err != nil {
-				return nil, err
+				f, err := process.resolveFile(v, loadContents)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, f)
+
+			case cwl.Directory:
+				d, err := process.resolveDir(cwl.Directory{Location: m.Location}, listing)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, d)
 			}
-			files = append(files, f)
 		}
 	}
 	return files, nil
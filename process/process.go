@@ -5,7 +5,6 @@ import (
 	"github.com/lijiang2014/cwl"
 	"github.com/lijiang2014/cwl/expr"
 	"github.com/rs/xid"
-	"log"
 )
 
 type Mebibyte int
@@ -17,7 +16,7 @@ type Runtime struct {
 	Tmpdir string
 	// TODO make these all strings?
 	RootHost string
-	Cores      string
+	Cores      int
 	RAM        Mebibyte
 	OutdirSize Mebibyte
 	TmpdirSize Mebibyte
@@ -45,8 +44,18 @@ type Process struct {
 	env            map[string]string
 	shell          bool
 	resources      Resources
+	scheduler      Scheduler
+	kubernetes     *Kubernetes
+	session        *Session
+	stdin          string
 	stdout         string
 	stderr         string
+	workdir        []WorkDirEntry
+	// listing describes how to load the listing of Directory values
+	// of the input being bound.
+	listing cwl.LoadListing
+	// exitCode is the exit code of the command, once it has run.
+	exitCode *int
 }
 
 func NewProcess(tool *cwl.Tool, values cwl.Values, rt Runtime, fs Filesystem) (*Process, error) {
@@ -56,6 +65,14 @@ func NewProcess(tool *cwl.Tool, values cwl.Values, rt Runtime, fs Filesystem) (*
 		return nil, err
 	}
 
+	// Resolve SchemaDefRequirement types in a copy of the tool,
+	// since the tool may be shared by concurrent jobs.
+	resolved := *tool
+	if err := resolved.ResolveSchemaDefs(); err != nil {
+		return nil, errf("resolving SchemaDefRequirement types: %s", err)
+	}
+	tool = &resolved
+
 	// TODO expose input bindings as an exported type of data
 	//      could be useful to know separately from all the other processing.
 	process := &Process{
@@ -77,6 +94,7 @@ func NewProcess(tool *cwl.Tool, values cwl.Values, rt Runtime, fs Filesystem) (*
 	for _, in := range tool.Inputs {
 		val := values[in.ID]
 		k := sortKey{getPos(in.InputBinding)}
+		process.listing = process.loadListing(in.LoadListing)
 		b, err := process.bindInput(in.ID, in.Type, in.InputBinding, in.SecondaryFiles, val, k)
 		if err != nil {
 			return nil, errf("binding input %q: %s", in.ID, err)
@@ -133,9 +151,44 @@ func NewProcess(tool *cwl.Tool, values cwl.Values, rt Runtime, fs Filesystem) (*
 	process.stdout = stdoutStr
 	process.stderr = stderrStr
 
+	process.stdin, err = process.evalStdin()
+	if err != nil {
+		return nil, err
+	}
+
 	return process, nil
 }
 
+// evalStdin evaluates the stdin expression to the path of the file
+// which is piped into the command's standard input stream.
+func (process *Process) evalStdin() (string, error) {
+	v, err := process.eval(process.tool.Stdin, nil)
+	if err != nil {
+		return "", wrap(err, "evaluating stdin expression")
+	}
+
+	switch z := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return z, nil
+	case map[string]interface{}:
+		// e.g. "stdin: $(inputs.file1)"
+		if f, err := toValue(z); err == nil {
+			if f, ok := f.(cwl.File); ok && f.Path != "" {
+				return f.Path, nil
+			}
+		}
+	}
+	return "", errf("stdin expression must return a path or a File, got %#v", v)
+}
+
+// Stdin returns the path of the file piped into the command's
+// standard input stream, or an empty string.
+func (process *Process) Stdin() string {
+	return process.stdin
+}
+
 func (process *Process) Stdout() string {
 	return process.stdout
 }
@@ -152,6 +205,21 @@ func (process *Process) Resources() Resources {
 	return process.resources
 }
 
+func (process *Process) Scheduler() Scheduler {
+	return process.scheduler
+}
+
+// Kubernetes returns the Kubernetes application described
+// by the tool's KubernetesRequirement, or nil.
+func (process *Process) Kubernetes() *Kubernetes {
+	return process.kubernetes
+}
+
+// Session returns the endpoint described by the tool's SessionRequirement, or nil.
+func (process *Process) Session() *Session {
+	return process.session
+}
+
 func (process *Process) Env() map[string]string {
 	env := map[string]string{}
 	for k, v := range process.env {
@@ -161,14 +229,29 @@ func (process *Process) Env() map[string]string {
 }
 
 func (process *Process) loadReqs() error {
-	reqs := append([]cwl.Requirement{}, process.tool.Requirements...)
-	reqs = append(reqs, process.tool.Hints...)
-
+	// Requirements take precedence over hints of the same class,
+	// so drop those hints rather than letting them override requirements.
+	reqs := cwl.MergeRequirements(process.tool.Requirements)
+	reqs = append(reqs, cwl.MergeHints(reqs, process.tool.Hints)...)
+
+	// Resources are evaluated first, since other expressions may refer
+	// to the resources reserved in "runtime". The expressionLib is set
+	// before, since resource expressions may call its functions.
+	var resources *cwl.ResourceRequirement
 	for _, req := range reqs {
 		switch z := req.(type) {
-
+		case cwl.ResourceRequirement:
+			resources = &z
 		case cwl.InlineJavascriptRequirement:
 			process.expressionLibs = z.ExpressionLib
+		}
+	}
+	if err := process.evalResources(resources); err != nil {
+		return errf("failed to evaluate ResourceRequirement: %s", err)
+	}
+
+	for _, req := range reqs {
+		switch z := req.(type) {
 
 		case cwl.EnvVarRequirement:
 			err := process.evalEnvVars(z.EnvDef)
@@ -176,60 +259,36 @@ func (process *Process) loadReqs() error {
 				return errf("failed to evaluate EnvVarRequirement: %s", err)
 			}
 
-		case cwl.ResourceRequirement:
-			// TODO eval expressions
-
 		case cwl.SchemaDefRequirement:
-			return errf("SchemaDefRequirement is not supported (yet)")
+			// Types are resolved when the process is created.
 		case cwl.InitialWorkDirRequirement:
-			//return errf("InitialWorkDirRequirement is not supported (yet)")
 			err := process.evalWorkDirRequirement(z.Listing)
 			if err != nil {
-				return errf("failed to evaluate EnvVarRequirement: %s", err)
+				return errf("failed to evaluate InitialWorkDirRequirement: %s", err)
 			}
-			return nil
-		}
-	}
-	return nil
-}
 
-func (process *Process) evalWorkDirRequirement(def []cwl.InitialWorkDirListing) error {
-	for _, filei := range def {
-		var (
-			filename, filedata string
-		)
-		{
-			expr := filei.Entry
-			val, err := process.eval(expr, nil)
+		case cwl.SchedulerRequirement:
+			err := process.evalScheduler(z)
 			if err != nil {
-				return errf(`failed to evaluate expression: "%s": %s`, expr, err)
+				return errf("failed to evaluate SchedulerRequirement: %s", err)
 			}
-			str, ok := val.(string)
-			if !ok {
-				return errf(`EnvVar must evaluate to a string, got "%s"`, val)
-			}
-			filedata = str
-		}
-		
-		{
-			expr := filei.Entryname
-			val, err := process.eval(expr, nil)
+
+		case cwl.ParallelRequirement:
+			process.scheduler.MPI = z.MpiEnabled
+
+		case cwl.KubernetesRequirement:
+			err := process.evalKubernetes(z)
 			if err != nil {
-				return errf(`failed to evaluate expression: "%s": %s`, expr, err)
+				return errf("failed to evaluate KubernetesRequirement: %s", err)
 			}
-			str, ok := val.(string)
-			if !ok {
-				return errf(`EnvVar must evaluate to a string, got "%s"`, val)
+
+		case cwl.SessionRequirement:
+			err := process.evalSession(z)
+			if err != nil {
+				return errf("failed to evaluate SessionRequirement: %s", err)
 			}
-			//log.Println("file i , file name", str)
-			filename = str
 		}
-		// write file into fs.
-		_ ,err := process.fs.Create(filename, filedata)
-		return err
-		//process.env[k] = str
 	}
-	log.Printf("skip InitialWorkDirRequirement %#v", def)
 	return nil
 }
 
@@ -268,17 +327,22 @@ func (process *Process) eval(x cwl.Expression, self interface{}) (interface{}, e
 	}
 
 	r := process.runtime
+	runtime := map[string]interface{}{
+		"outdir":     r.Outdir,
+		"tmpdir":     r.Tmpdir,
+		"cores":      r.Cores,
+		"ram":        r.RAM,
+		"outdirSize": r.OutdirSize,
+		"tmpdirSize": r.TmpdirSize,
+	}
+	// The exit code is known once the command has run, i.e. in outputEval.
+	if process.exitCode != nil {
+		runtime["exitCode"] = *process.exitCode
+	}
 	return expr.Eval(x, process.expressionLibs, map[string]interface{}{
-		"inputs": inputsData,
-		"self":   selfData,
-		"runtime": map[string]interface{}{
-			"outdir":     r.Outdir,
-			"tmpdir":     r.Tmpdir,
-			"cores":      r.Cores,
-			"ram":        r.RAM,
-			"outdirSize": r.OutdirSize,
-			"tmpdirSize": r.TmpdirSize,
-		},
+		"inputs":  inputsData,
+		"self":    selfData,
+		"runtime": runtime,
 	})
 }
 
@@ -305,8 +369,8 @@ func toJSONMap(v interface{}) (interface{}, error) {
 // setDefaults sets the default input values based on the CommandInput.Default.
 func setDefaults(values cwl.Values, inputs []cwl.CommandInput) {
 	for _, in := range inputs {
-		_, ok := values[in.ID]
-		if !ok && in.Default != nil {
+		v, ok := values[in.ID]
+		if (!ok || v == nil) && in.Default != nil {
 			values[in.ID] = in.Default
 		}
 	}
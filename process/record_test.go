@@ -0,0 +1,124 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+const recordTool = `
+class: CommandLineTool
+cwlVersion: v1.0
+baseCommand: echo
+inputs:
+  dependent:
+    type:
+      type: record
+      fields:
+        itemA:
+          type: string
+          inputBinding:
+            position: 2
+            prefix: -A
+        itemB:
+          type: string
+          inputBinding:
+            prefix: -B
+  exclusive:
+    type:
+      - type: record
+        fields:
+          itemC:
+            type: string
+            inputBinding:
+              prefix: -C
+      - type: record
+        fields:
+          itemD:
+            type: string
+            inputBinding:
+              prefix: -D
+  prefixed:
+    inputBinding:
+      position: 1
+      prefix: -R
+    type:
+      type: record
+      fields:
+        second:
+          type: int
+          inputBinding:
+            position: 2
+        first:
+          type: int
+          inputBinding:
+            position: 1
+        unbound: string?
+outputs:
+  out:
+    type:
+      type: record
+      fields:
+        a:
+          type: string
+          outputBinding:
+            outputEval: $(inputs.dependent.itemA)
+        n:
+          type: int
+          outputBinding:
+            outputEval: $(inputs.prefixed.first + inputs.prefixed.second)
+`
+
+func TestRecordCommand(t *testing.T) {
+	tool := loadDoc(t, recordTool).(*cwl.Tool)
+
+	run := func(exclusive map[string]cwl.Value) *Process {
+		proc, err := NewProcess(tool, cwl.Values{
+			"dependent": map[string]cwl.Value{"itemA": "one", "itemB": "two"},
+			"exclusive": exclusive,
+			"prefixed":  map[string]cwl.Value{"first": 1, "second": 2},
+		}, Runtime{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return proc
+	}
+
+	expect := []string{"echo", "-B", "two", "-D", "four", "-R", "1", "2", "-A", "one"}
+	proc := run(map[string]cwl.Value{"itemD": "four"})
+	cmd, err := proc.Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cmd, expect) {
+		t.Errorf("expected %v, got %v", expect, cmd)
+	}
+
+	// Record fields are only available through their record.
+	if v, err := proc.eval("$(inputs.itemA)", nil); err != nil || v != nil {
+		t.Errorf("expected record field to be hidden from inputs, got %#v %v", v, err)
+	}
+
+	out, err := proc.Outputs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, ok := out["out"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a record output, got %#v", out["out"])
+	}
+	if rec["a"] != "one" || rec["n"] != int32(3) {
+		t.Errorf("unexpected record output %#v", rec)
+	}
+
+	// A value with the fields of both records doesn't match either.
+	_, err = NewProcess(tool, cwl.Values{
+		"dependent": map[string]cwl.Value{"itemA": "one", "itemB": "two"},
+		"exclusive": map[string]cwl.Value{"itemC": "three", "itemD": "four"},
+		"prefixed":  map[string]cwl.Value{"first": 1, "second": 2},
+	}, Runtime{}, nil)
+	if err == nil {
+		t.Error("expected error for a value matching no record of the union")
+	}
+}
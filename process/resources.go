@@ -0,0 +1,122 @@
+package process
+
+import (
+	"math"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/spf13/cast"
+)
+
+// Default resource requests, which apply to resources not requested by a tool.
+// http://www.commonwl.org/v1.0/CommandLineTool.html#ResourceRequirement
+const (
+	DefaultCores               = 1
+	DefaultRAM        Mebibyte = 256
+	DefaultTmpdirSize Mebibyte = 1024
+	DefaultOutdirSize Mebibyte = 1024
+)
+
+// evalResources evaluates the resources requested by the tool, which may be nil,
+// into process.resources. The runtime reserves the minimum requested resources,
+// unless the engine's Runtime already set them, e.g. from a scheduler's allocation.
+func (process *Process) evalResources(req *cwl.ResourceRequirement) error {
+	if req == nil {
+		req = &cwl.ResourceRequirement{}
+	}
+
+	coresMin, coresMax, err := process.evalResourceRange("cores", req.CoresMin, req.CoresMax, DefaultCores)
+	if err != nil {
+		return err
+	}
+	ramMin, ramMax, err := process.evalResourceRange("ram", req.RAMMin, req.RAMMax, float64(DefaultRAM))
+	if err != nil {
+		return err
+	}
+	tmpMin, tmpMax, err := process.evalResourceRange("tmpdir", req.TmpDirMin, req.TmpDirMax, float64(DefaultTmpdirSize))
+	if err != nil {
+		return err
+	}
+	outMin, outMax, err := process.evalResourceRange("outdir", req.OutDirMin, req.OutDirMax, float64(DefaultOutdirSize))
+	if err != nil {
+		return err
+	}
+
+	// Resources are reserved in whole cores and mebibytes.
+	r := Resources{
+		CoresMin:  int(math.Ceil(coresMin)),
+		CoresMax:  int(math.Ceil(coresMax)),
+		RAMMin:    Mebibyte(math.Ceil(ramMin)),
+		RAMMax:    Mebibyte(math.Ceil(ramMax)),
+		TmpdirMin: Mebibyte(math.Ceil(tmpMin)),
+		TmpdirMax: Mebibyte(math.Ceil(tmpMax)),
+		OutdirMin: Mebibyte(math.Ceil(outMin)),
+		OutdirMax: Mebibyte(math.Ceil(outMax)),
+	}
+	process.resources = r
+
+	rt := &process.runtime
+	if rt.Cores == 0 {
+		rt.Cores = r.CoresMin
+	}
+	if rt.RAM == 0 {
+		rt.RAM = r.RAMMin
+	}
+	if rt.TmpdirSize == 0 {
+		rt.TmpdirSize = r.TmpdirMin
+	}
+	if rt.OutdirSize == 0 {
+		rt.OutdirSize = r.OutdirMin
+	}
+	return nil
+}
+
+// evalResourceRange evaluates the min and max request of a resource.
+//
+// cwl spec:
+// "If coresMin is specified but coresMax is not, coresMax is the same
+// as coresMin. If coresMax is specified but coresMin is not, coresMin
+// is the same as coresMax." (and so on for the other resources)
+func (process *Process) evalResourceRange(name string, minExpr, maxExpr cwl.Expression, def float64) (float64, float64, error) {
+	min, err := process.evalResource(minExpr)
+	if err != nil {
+		return 0, 0, errf("%sMin: %s", name, err)
+	}
+	max, err := process.evalResource(maxExpr)
+	if err != nil {
+		return 0, 0, errf("%sMax: %s", name, err)
+	}
+
+	switch {
+	case minExpr == "" && maxExpr == "":
+		min, max = def, def
+	case minExpr == "":
+		min = max
+	case maxExpr == "":
+		max = min
+	}
+
+	if min > max {
+		return 0, 0, errf("%sMin (%v) is greater than %sMax (%v)", name, min, name, max)
+	}
+	return min, max, nil
+}
+
+// evalResource evaluates a resource request, which is either a number
+// or an expression returning a number.
+func (process *Process) evalResource(x cwl.Expression) (float64, error) {
+	if x == "" {
+		return 0, nil
+	}
+	v, err := process.eval(x, nil)
+	if err != nil {
+		return 0, err
+	}
+	n, err := cast.ToFloat64E(v)
+	if err != nil {
+		return 0, errf("expected a number, got %#v", v)
+	}
+	if n < 0 {
+		return 0, errf("expected a positive number, got %v", n)
+	}
+	return n, nil
+}
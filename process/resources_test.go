@@ -0,0 +1,108 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+func TestResources(t *testing.T) {
+	doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  ResourceRequirement:
+    coresMin: $(inputs.n)
+    ramMax: 512
+    outdirMin: 10
+    outdirMax: 20
+inputs:
+  n: int
+outputs: []
+baseCommand: echo
+arguments: [$(runtime.cores), $(runtime.ram), $(runtime.outdirSize)]
+`)
+	tool := doc.(*cwl.Tool)
+
+	proc, err := NewProcess(tool, cwl.Values{"n": 3}, Runtime{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := Resources{
+		CoresMin: 3, CoresMax: 3,
+		RAMMin: 512, RAMMax: 512,
+		OutdirMin: 10, OutdirMax: 20,
+		TmpdirMin: DefaultTmpdirSize, TmpdirMax: DefaultTmpdirSize,
+	}
+	if proc.Resources() != expect {
+		t.Errorf("expected %+v, got %+v", expect, proc.Resources())
+	}
+
+	cmd, err := proc.Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cmd, []string{"echo", "3", "512", "10"}) {
+		t.Errorf("unexpected command %v", cmd)
+	}
+
+	// Resources reserved by the engine aren't overridden.
+	proc, err = NewProcess(tool, cwl.Values{"n": 3}, Runtime{Cores: 4}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd, _ := proc.Command(); cmd[1] != "4" {
+		t.Errorf("expected the engine's cores, got %v", cmd)
+	}
+
+	// coresMin is greater than coresMax.
+	tool.Requirements = []cwl.Requirement{
+		cwl.ResourceRequirement{CoresMin: "$(inputs.n)", CoresMax: "2"},
+	}
+	if _, err := NewProcess(tool, cwl.Values{"n": 3}, Runtime{}, nil); err == nil {
+		t.Error("expected error for coresMin greater than coresMax")
+	}
+}
+
+func TestResourcesExpressionLib(t *testing.T) {
+	doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  InlineJavascriptRequirement:
+    expressionLib:
+      - "function ram(n) { return n * 256; }"
+  ResourceRequirement:
+    ramMin: $(ram(inputs.n))
+inputs:
+  n: int
+outputs: []
+baseCommand: echo
+`)
+	proc, err := NewProcess(doc.(*cwl.Tool), cwl.Values{"n": 2}, Runtime{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proc.Resources().RAMMin != 512 {
+		t.Errorf("expected ramMin 512, got %+v", proc.Resources())
+	}
+}
+
+func TestDefaultResources(t *testing.T) {
+	doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+inputs: []
+outputs: []
+baseCommand: echo
+`)
+	proc, err := NewProcess(doc.(*cwl.Tool), cwl.Values{}, Runtime{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := proc.Resources()
+	if r.CoresMin != DefaultCores || r.RAMMin != DefaultRAM || r.OutdirMax != DefaultOutdirSize {
+		t.Errorf("expected default resources, got %+v", r)
+	}
+}
@@ -0,0 +1,97 @@
+package process
+
+import (
+	"fmt"
+)
+
+// Status classifies the result of running a process.
+// http://www.commonwl.org/v1.0/CommandLineTool.html#CommandLineTool
+type Status string
+
+const (
+	Success Status = "success"
+	// TemporaryFail means the process failed due to a possibly temporary
+	// condition, so running it again may succeed.
+	TemporaryFail Status = "temporaryFail"
+	// PermanentFail means the process is expected to always fail
+	// with the same runtime environment and inputs.
+	PermanentFail Status = "permanentFail"
+)
+
+// ExitStatus classifies the exit code of the process' command using
+// the tool's successCodes, temporaryFailCodes and permanentFailCodes.
+// Codes not listed by the tool are a success if zero, otherwise a permanentFail.
+func (process *Process) ExitStatus(code int) Status {
+	has := func(codes []int) bool {
+		for _, c := range codes {
+			if c == code {
+				return true
+			}
+		}
+		return false
+	}
+
+	tool := process.tool
+	switch {
+	case has(tool.SuccessCodes):
+		return Success
+	case has(tool.TemporaryFailCodes):
+		return TemporaryFail
+	case has(tool.PermanentFailCodes):
+		return PermanentFail
+	case code == 0:
+		return Success
+	}
+	return PermanentFail
+}
+
+// ExitError returns an error describing the exit code of the process' command,
+// or nil if the code is classified as a success. Executors use it to report
+// the exit code of a command, which output expressions see as runtime.exitCode.
+func (process *Process) ExitError(code int) error {
+	process.exitCode = &code
+	status := process.ExitStatus(code)
+	if status == Success {
+		return nil
+	}
+	return &ExitError{Code: code, Status: status}
+}
+
+// ExitError is the error of a command which exited with a failure code.
+type ExitError struct {
+	Code   int
+	Status Status
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d (%s)", e.Code, e.Status)
+}
+
+// StatusOf classifies the error returned by running a process.
+// Errors other than command failures, e.g. invalid inputs, are permanent.
+func StatusOf(err error) Status {
+	switch z := err.(type) {
+	case nil:
+		return Success
+	case *ExitError:
+		return z.Status
+	case *statusError:
+		return z.status
+	}
+	return PermanentFail
+}
+
+// statusError adds context to an error while keeping its status.
+type statusError struct {
+	msg    string
+	status Status
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// wrapStatus is wrap for errors which may carry a Status.
+func wrapStatus(err error, msg string, args ...interface{}) error {
+	return &statusError{wrap(err, msg, args...).Error(), StatusOf(err)}
+}
@@ -0,0 +1,106 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+const exitCodeTool = `
+class: CommandLineTool
+cwlVersion: v1.0
+baseCommand: "true"
+successCodes: [1]
+temporaryFailCodes: [2]
+permanentFailCodes: [0]
+inputs: []
+outputs: []
+`
+
+func TestExitStatus(t *testing.T) {
+	proc, err := NewProcess(loadDoc(t, exitCodeTool).(*cwl.Tool), cwl.Values{}, Runtime{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[int]Status{
+		0: PermanentFail,
+		1: Success,
+		2: TemporaryFail,
+		3: PermanentFail,
+	}
+	for code, status := range expect {
+		if got := proc.ExitStatus(code); got != status {
+			t.Errorf("exit code %d: expected %s, got %s", code, status, got)
+		}
+		if got := StatusOf(proc.ExitError(code)); got != status {
+			t.Errorf("exit error %d: expected %s, got %s", code, status, got)
+		}
+	}
+}
+
+func TestRetryTemporaryFail(t *testing.T) {
+	doc := loadDoc(t, `
+class: Workflow
+cwlVersion: v1.0
+inputs: []
+outputs: []
+steps:
+  step1:
+    in: []
+    out: []
+    run:
+      `+indent(exitCodeTool)+`
+`)
+	wf := doc.(*cwl.Workflow)
+
+	// A temporaryFail is retried.
+	exec := &exitExecutor{codes: []int{2, 2, 1}}
+	e := &Engine{Executor: exec, Retries: 2}
+	if _, err := e.RunWorkflow(wf, cwl.Values{}); err != nil {
+		t.Fatal(err)
+	}
+	if exec.runs != 3 {
+		t.Errorf("expected 3 runs, got %d", exec.runs)
+	}
+
+	// The status of the last failure is reported through the workflow.
+	exec = &exitExecutor{codes: []int{2, 2}}
+	e = &Engine{Executor: exec, Retries: 1}
+	_, err := e.RunWorkflow(wf, cwl.Values{})
+	if StatusOf(err) != TemporaryFail {
+		t.Errorf("expected temporaryFail, got %s: %v", StatusOf(err), err)
+	}
+
+	// A permanentFail isn't retried.
+	exec = &exitExecutor{codes: []int{0}}
+	e = &Engine{Executor: exec, Retries: 2}
+	_, err = e.RunWorkflow(wf, cwl.Values{})
+	if StatusOf(err) != PermanentFail || exec.runs != 1 {
+		t.Errorf("expected a single permanentFail run, got %s after %d runs", StatusOf(err), exec.runs)
+	}
+}
+
+func TestRuntimeExitCode(t *testing.T) {
+	tool := loadDoc(t, `
+cwlVersion: v1.1
+class: CommandLineTool
+baseCommand: "false"
+successCodes: [3]
+inputs: []
+outputs:
+  code:
+    type: int
+    outputBinding:
+      outputEval: $(runtime.exitCode)
+`).(*cwl.Tool)
+
+	e := &Engine{Executor: &exitExecutor{codes: []int{3}}}
+	out, err := e.RunTool(tool, cwl.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["code"] != int32(3) {
+		t.Errorf("expected 3, got %#v", out["code"])
+	}
+}
@@ -0,0 +1,111 @@
+package process
+
+import (
+	"github.com/lijiang2014/cwl"
+)
+
+/*** CWL workflow step scatter code ***/
+
+// scatterJobs expands the input object of a scattered step into the input
+// objects of the individual jobs, according to the scatter method.
+// http://www.commonwl.org/v1.0/Workflow.html#WorkflowStep
+//
+// `params` are the IDs of the scattered inputs.
+//
+// The jobs are returned in row-major order, along with the shape of the
+// scatter, which is used by gatherOutputs to nest the job outputs.
+func scatterJobs(inputs cwl.Values, params []string, method cwl.ScatterMethod) ([]cwl.Values, []int, error) {
+	var arrays [][]cwl.Value
+	for _, p := range params {
+		val := inputs[p]
+		arr, ok := val.([]cwl.Value)
+		if !ok {
+			return nil, nil, errf("scatter parameter %q is not an array, got %#v", p, val)
+		}
+		arrays = append(arrays, arr)
+	}
+
+	if len(params) == 1 {
+		method = cwl.DotProduct
+	}
+
+	switch method {
+	case cwl.DotProduct, "":
+		// cwl spec:
+		// "dotproduct specifies that each of the input arrays are aligned and
+		// one element taken from each array to construct each job.
+		// It is an error if all input arrays are not the same length."
+		n := len(arrays[0])
+		for i, arr := range arrays {
+			if len(arr) != n {
+				return nil, nil, errf(
+					"dotproduct scatter requires arrays of the same length, but %q has length %d and %q has length %d",
+					params[0], n, params[i], len(arr))
+			}
+		}
+
+		var jobs []cwl.Values
+		for i := 0; i < n; i++ {
+			job := copyValues(inputs)
+			for j, p := range params {
+				job[p] = arrays[j][i]
+			}
+			jobs = append(jobs, job)
+		}
+		return jobs, []int{n}, nil
+
+	case cwl.NestedCrossProduct, cwl.FlatCrossProduct:
+		// cwl spec:
+		// "nested_crossproduct specifies the Cartesian product of the inputs,
+		// producing a job for every combination of the scattered inputs.
+		// The output must be nested arrays for each level of scattering,
+		// in the order that the input arrays are listed in the scatter field."
+		//
+		// "flat_crossproduct specifies the Cartesian product of the inputs,
+		// producing a job for every combination of the scattered inputs.
+		// The output arrays must be flattened to a single level, but otherwise
+		// listed in the order that the input arrays are listed in the scatter field."
+		shape := make([]int, len(arrays))
+		for i, arr := range arrays {
+			shape[i] = len(arr)
+		}
+
+		jobs := []cwl.Values{copyValues(inputs)}
+		for j, p := range params {
+			var next []cwl.Values
+			for _, job := range jobs {
+				for _, item := range arrays[j] {
+					x := copyValues(job)
+					x[p] = item
+					next = append(next, x)
+				}
+			}
+			jobs = next
+		}
+
+		if method == cwl.FlatCrossProduct {
+			shape = []int{len(jobs)}
+		}
+		return jobs, shape, nil
+	}
+
+	return nil, nil, errf("unknown scatter method %q", method)
+}
+
+// gatherOutputs nests the values of a single output of a scattered step's
+// jobs (given in row-major order) into arrays according to the scatter shape.
+func gatherOutputs(vals []cwl.Value, shape []int) cwl.Value {
+	out := []cwl.Value{}
+	if len(shape) == 1 {
+		return append(out, vals...)
+	}
+
+	size := 1
+	for _, n := range shape[1:] {
+		size *= n
+	}
+	for i := 0; i < shape[0]; i++ {
+		out = append(out, gatherOutputs(vals[i*size:(i+1)*size], shape[1:]))
+	}
+	return out
+}
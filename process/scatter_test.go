@@ -0,0 +1,142 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/lijiang2014/cwl"
+)
+
+func TestScatterGather(t *testing.T) {
+	a := []cwl.Value{"a1", "a2"}
+	b := []cwl.Value{"b1", "b2", "b3"}
+	empty := []cwl.Value{}
+
+	tests := []struct {
+		name   string
+		method cwl.ScatterMethod
+		inputs cwl.Values
+		params []string
+		expect cwl.Value
+	}{
+		{
+			name:   "single",
+			inputs: cwl.Values{"a": a, "c": "c"},
+			params: []string{"a"},
+			expect: []cwl.Value{"a1 c", "a2 c"},
+		},
+		{
+			name:   "dotproduct",
+			method: cwl.DotProduct,
+			inputs: cwl.Values{"a": a, "b": b[:2]},
+			params: []string{"a", "b"},
+			expect: []cwl.Value{"a1 b1", "a2 b2"},
+		},
+		{
+			name:   "nested_crossproduct",
+			method: cwl.NestedCrossProduct,
+			inputs: cwl.Values{"a": a, "b": b},
+			params: []string{"a", "b"},
+			expect: []cwl.Value{
+				[]cwl.Value{"a1 b1", "a1 b2", "a1 b3"},
+				[]cwl.Value{"a2 b1", "a2 b2", "a2 b3"},
+			},
+		},
+		{
+			name:   "flat_crossproduct",
+			method: cwl.FlatCrossProduct,
+			inputs: cwl.Values{"a": a, "b": b},
+			params: []string{"a", "b"},
+			expect: []cwl.Value{"a1 b1", "a1 b2", "a1 b3", "a2 b1", "a2 b2", "a2 b3"},
+		},
+		{
+			name:   "empty",
+			inputs: cwl.Values{"a": empty},
+			params: []string{"a"},
+			expect: []cwl.Value{},
+		},
+		{
+			name:   "nested_crossproduct empty inner",
+			method: cwl.NestedCrossProduct,
+			inputs: cwl.Values{"a": a, "b": empty},
+			params: []string{"a", "b"},
+			expect: []cwl.Value{[]cwl.Value{}, []cwl.Value{}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			jobs, shape, err := scatterJobs(test.inputs, test.params, test.method)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Fake a job output by joining the job's input values.
+			var outs []cwl.Value
+			for _, job := range jobs {
+				out := ""
+				for _, p := range append(test.params, "c") {
+					if v, ok := job[p]; ok {
+						if out != "" {
+							out += " "
+						}
+						out += v.(string)
+					}
+				}
+				outs = append(outs, out)
+			}
+
+			res := gatherOutputs(outs, shape)
+			if !reflect.DeepEqual(res, test.expect) {
+				t.Errorf("unexpected gathered outputs")
+				for _, d := range pretty.Diff(res, test.expect) {
+					t.Log(d)
+				}
+			}
+		})
+	}
+}
+
+func TestScatterDotProductLengthMismatch(t *testing.T) {
+	inputs := cwl.Values{
+		"a": []cwl.Value{"a1", "a2"},
+		"b": []cwl.Value{"b1"},
+	}
+	_, _, err := scatterJobs(inputs, []string{"a", "b"}, cwl.DotProduct)
+	if err == nil {
+		t.Error("expected error for arrays of different lengths")
+	}
+}
+
+func TestRunScatterWorkflow(t *testing.T) {
+	doc := loadDoc(t, `
+class: Workflow
+cwlVersion: v1.0
+requirements:
+  - class: ScatterFeatureRequirement
+inputs:
+  xs: int[]
+outputs:
+  result:
+    type: int[]
+    outputSource: step1/out
+steps:
+  step1:
+    scatter: n
+    in:
+      n: xs
+    out: [out]
+    run:
+`+indent(incTool))
+
+	e := &Engine{Executor: nopExecutor{}, Parallel: 2}
+	out, err := e.Run(doc, cwl.Values{"xs": []cwl.Value{1, 2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []cwl.Value{int32(2), int32(3), int32(4)}
+	if !reflect.DeepEqual(out["result"], expect) {
+		t.Errorf("expected %#v, got %#v", expect, out["result"])
+	}
+}
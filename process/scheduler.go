@@ -0,0 +1,85 @@
+package process
+
+import (
+	"github.com/lijiang2014/cwl"
+	"github.com/spf13/cast"
+)
+
+// Scheduler describes how a batch scheduler runs a job, from the
+// SchedulerRequirement and ParallelRequirement of the tool.
+// Zero values leave the choice to the executor.
+type Scheduler struct {
+	// Name is the scheduler required by the tool, e.g. "slurm".
+	Name      string
+	Cluster   string
+	Partition string
+	Nodes     int
+	// Args are extra arguments passed to the scheduler.
+	Args []string
+	// MPI is true if the command must be launched as an MPI program,
+	// e.g. with srun or mpirun.
+	MPI bool
+}
+
+// evalScheduler evaluates the expressions of a SchedulerRequirement
+// into process.scheduler.
+func (process *Process) evalScheduler(req cwl.SchedulerRequirement) error {
+	s := &process.scheduler
+	s.Name = req.Scheduler
+
+	var err error
+	if s.Cluster, err = process.evalSchedulerString("cluster", req.Cluster); err != nil {
+		return err
+	}
+	if s.Partition, err = process.evalSchedulerString("partition", req.Partition); err != nil {
+		return err
+	}
+
+	if req.Nodes != "" {
+		val, err := process.eval(req.Nodes, nil)
+		if err != nil {
+			return errf("failed to evaluate nodes: %s", err)
+		}
+		if val != nil {
+			n, err := cast.ToIntE(val)
+			if err != nil {
+				return errf("nodes must be an integer, got %v", val)
+			}
+			if n < 0 {
+				return errf("nodes must not be negative, got %d", n)
+			}
+			s.Nodes = n
+		}
+	}
+
+	s.Args = nil
+	for i, arg := range req.SchedulerArgs {
+		str, err := process.evalSchedulerString("args", arg)
+		if err != nil {
+			return errf("arg %d: %s", i, err)
+		}
+		if str != "" {
+			s.Args = append(s.Args, str)
+		}
+	}
+	return nil
+}
+
+// evalSchedulerString evaluates an optional string field of a SchedulerRequirement.
+func (process *Process) evalSchedulerString(name string, x cwl.Expression) (string, error) {
+	if x == "" {
+		return "", nil
+	}
+	val, err := process.eval(x, nil)
+	if err != nil {
+		return "", errf("failed to evaluate %s: %s", name, err)
+	}
+	if val == nil {
+		return "", nil
+	}
+	str, err := cast.ToStringE(val)
+	if err != nil {
+		return "", errf("%s must be a string, got %v", name, val)
+	}
+	return str, nil
+}
@@ -0,0 +1,195 @@
+package process
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+func TestSchemaDef(t *testing.T) {
+	doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  SchemaDefRequirement:
+    types:
+      - name: Level
+        type: enum
+        symbols: [low, high]
+      - name: Settings
+        type: record
+        fields:
+          level:
+            type: "#Level"
+            inputBinding:
+              prefix: --level
+          names:
+            type:
+              type: array
+              items: string
+            inputBinding:
+              prefix: --names
+baseCommand: echo
+inputs:
+  settings:
+    type: "#Settings"
+    inputBinding: {}
+outputs:
+  out:
+    type: "#Settings"
+    outputBinding:
+      outputEval: $(inputs.settings)
+`)
+	tool := doc.(*cwl.Tool)
+
+	proc, err := NewProcess(tool, cwl.Values{
+		"settings": map[string]cwl.Value{
+			"level": "high",
+			"names": []cwl.Value{"a", "b"},
+		},
+	}, Runtime{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd, err := proc.Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"echo", "--level", "high", "--names", "a", "b"}
+	if !reflect.DeepEqual(cmd, expect) {
+		t.Errorf("expected %v, got %v", expect, cmd)
+	}
+
+	out, err := proc.Outputs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, ok := out["out"].(map[string]interface{})
+	if !ok || rec["level"] != "high" {
+		t.Errorf("unexpected record output %#v", out["out"])
+	}
+
+	// The tool itself isn't modified.
+	if _, ok := tool.Inputs[0].Type[0].(cwl.TypeRef); !ok {
+		t.Error("expected the tool's input type to be unchanged")
+	}
+}
+
+func TestSchemaDefCycle(t *testing.T) {
+	doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  SchemaDefRequirement:
+    types:
+      - name: A
+        type: record
+        fields:
+          b: "#B"
+      - name: B
+        type: record
+        fields:
+          a: "#A"
+baseCommand: echo
+inputs:
+  in: "#A"
+outputs: []
+`)
+
+	_, err := NewProcess(doc.(*cwl.Tool), cwl.Values{}, Runtime{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "A -> B -> A") {
+		t.Errorf("expected circular reference error, got %v", err)
+	}
+}
+
+func TestSchemaDefImport(t *testing.T) {
+	for _, p := range []string{
+		"../examples/057-schemadef-tool/tool.cwl",
+		"../examples/058-schemadef-wf/tool.cwl",
+	} {
+		doc, err := cwl.Load(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs := cwl.Values{
+			"hello": map[string]cwl.Value{"a": "hello", "b": "world"},
+		}
+
+		switch z := doc.(type) {
+		case *cwl.Tool:
+			proc, err := NewProcess(z, inputs, Runtime{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd, err := proc.Command()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect := []string{"echo", "hello/world"}
+			if !reflect.DeepEqual(cmd, expect) {
+				t.Errorf("expected %v, got %v", expect, cmd)
+			}
+
+		case *cwl.Workflow:
+			if err := z.ResolveSchemaDefs(); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := z.Inputs[0].Type[0].(cwl.InputRecord); !ok {
+				t.Errorf("expected a record input type, got %#v", z.Inputs[0].Type)
+			}
+		}
+	}
+}
+
+func TestSchemaDefSubworkflow(t *testing.T) {
+	sub := `
+class: Workflow
+cwlVersion: v1.0
+requirements:
+  SchemaDefRequirement:
+    types:
+      - name: A
+        type: record
+        fields:
+          b: "#B"
+      - name: B
+        type: record
+        fields:
+          n: int
+inputs:
+  a: "#A"
+outputs: []
+steps: []
+`
+	wf := `
+class: Workflow
+cwlVersion: v1.0
+requirements:
+  - class: SubworkflowFeatureRequirement
+inputs:
+  a: Any
+outputs: []
+steps:
+  sub:
+    in:
+      a: a
+    out: []
+    run:
+`
+	doc := loadDoc(t, wf+indent(sub))
+	inputs := cwl.Values{"a": map[string]cwl.Value{"b": map[string]cwl.Value{"n": 1}}}
+	e := &Engine{Executor: nopExecutor{}}
+	if _, err := e.Run(doc, inputs); err != nil {
+		t.Fatal(err)
+	}
+
+	cycle := strings.Replace(sub, "n: int", `a: "#A"`, 1)
+	doc = loadDoc(t, wf+indent(cycle))
+	_, err := e.Run(doc, inputs)
+	if err == nil || !strings.Contains(err.Error(), "A -> B -> A") {
+		t.Errorf("expected circular reference error in subworkflow, got %v", err)
+	}
+}
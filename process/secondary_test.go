@@ -0,0 +1,105 @@
+package process
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+const secondaryTool = `
+class: CommandLineTool
+cwlVersion: v1.2
+baseCommand: cat
+inputs:
+  bam:
+    type: File
+    inputBinding: {}
+    secondaryFiles:
+      - .bai
+      - pattern: ^.fai
+        required: false
+outputs: []
+`
+
+func TestSecondaryFiles(t *testing.T) {
+	tool := loadDoc(t, secondaryTool).(*cwl.Tool)
+	fs := checksumFS{"/data/x.bam": "", "/data/x.bam.bai": ""}
+	proc, err := NewProcess(tool, cwl.Values{
+		"bam": cwl.File{Location: "/data/x.bam"},
+	}, Runtime{RootHost: "/job"}, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The optional .fai is missing, and the .bai is staged next to the bam.
+	bam := proc.InputBindings()[0].Value.(cwl.File)
+	if len(bam.SecondaryFiles) != 1 {
+		t.Fatalf("expected one secondary file, got %#v", bam.SecondaryFiles)
+	}
+	bai := bam.SecondaryFiles[0].(cwl.File)
+	if bai.Location != "file:///data/x.bam.bai" || bai.Path != "/job/inputs/data/x.bam.bai" {
+		t.Errorf("unexpected secondary file %#v", bai)
+	}
+
+	job, err := NewJob(proc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var staged []string
+	for _, in := range job.Inputs {
+		staged = append(staged, in.Path)
+	}
+	expected := []string{"/job/inputs/data/x.bam", "/job/inputs/data/x.bam.bai"}
+	if !reflect.DeepEqual(staged, expected) {
+		t.Errorf("expected staged inputs %v, got %v", expected, staged)
+	}
+}
+
+func TestSecondaryFilesMissing(t *testing.T) {
+	tool := loadDoc(t, secondaryTool).(*cwl.Tool)
+	fs := checksumFS{"/data/x.bam": ""}
+	_, err := NewProcess(tool, cwl.Values{
+		"bam": cwl.File{Location: "/data/x.bam"},
+	}, Runtime{}, fs)
+	if err == nil || !strings.Contains(err.Error(), "resolving secondary files") {
+		t.Errorf("expected an error for a missing required secondary file, got %v", err)
+	}
+}
+
+func TestSecondaryFilesPattern(t *testing.T) {
+	tests := []struct {
+		path    string
+		pattern string
+		expect  string
+	}{
+		{
+			path:    "/data/foo.bam",
+			pattern: "^.bai",
+			expect:  "/data/foo.bai",
+		},
+		{
+			path:    "/data/foo.bam",
+			pattern: ".bai",
+			expect:  "/data/foo.bam.bai",
+		},
+		{
+			path:    "/data/foo.vcf.gz",
+			pattern: "^^.idx",
+			expect:  "/data/foo.idx",
+		},
+	}
+
+	for _, test := range tests {
+		proc := &Process{fs: checksumFS{test.expect: ""}}
+		f, err := proc.resolveSecondaryFiles(cwl.File{Location: test.path}, cwl.SecondaryFile{Pattern: cwl.Expression(test.pattern)}, true)
+		if err != nil {
+			t.Errorf("%s %s: %s", test.path, test.pattern, err)
+			continue
+		}
+		if loc := f.SecondaryFiles[0].(cwl.File).Location; loc != "file://"+test.expect {
+			t.Errorf("%s %s: expected %s, got %s", test.path, test.pattern, test.expect, loc)
+		}
+	}
+}
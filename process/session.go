@@ -0,0 +1,132 @@
+package process
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/spf13/cast"
+)
+
+// Session describes the endpoint exposed by an interactive session tool,
+// from its SessionRequirement. A session job is reachable at its endpoint
+// from the time its command is running until it ends.
+type Session struct {
+	Port int
+	// Protocol of the endpoint, e.g. "http" or "vnc". Defaults to "http".
+	Protocol string
+	// Path of the endpoint's URL, if any.
+	Path string
+}
+
+// evalSession evaluates the expressions of a SessionRequirement.
+func (process *Process) evalSession(req cwl.SessionRequirement) error {
+	s := &Session{Protocol: req.Protocol}
+	if s.Protocol == "" {
+		s.Protocol = "http"
+	}
+
+	val, err := process.eval(req.Port, nil)
+	if err != nil {
+		return errf("failed to evaluate port: %s", err)
+	}
+	if s.Port, err = cast.ToIntE(val); err != nil || s.Port <= 0 {
+		return errf("port must be a positive integer, got %v", val)
+	}
+
+	if req.Path != "" {
+		val, err := process.eval(req.Path, nil)
+		if err != nil {
+			return errf("failed to evaluate path: %s", err)
+		}
+		if s.Path, err = cast.ToStringE(val); err != nil {
+			return errf("path must be a string, got %v", val)
+		}
+	}
+
+	process.session = s
+	return nil
+}
+
+// Endpoint is the address at which a running session job is reachable.
+type Endpoint struct {
+	JobID    string
+	Host     string
+	Port     int
+	Protocol string
+	URL      string
+}
+
+func newEndpoint(job *Job, host string) Endpoint {
+	s := job.Session
+	path := s.Path
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return Endpoint{
+		JobID:    job.ID,
+		Host:     host,
+		Port:     s.Port,
+		Protocol: s.Protocol,
+		URL:      fmt.Sprintf("%s://%s:%d%s", s.Protocol, host, s.Port, path),
+	}
+}
+
+// Sessions records the endpoints of running session jobs,
+// see NewSessionExecutor.
+type Sessions interface {
+	// Open records the endpoint of a session job once it's running.
+	Open(ep Endpoint) error
+	// Close removes the endpoint of a session job which has ended.
+	Close(ep Endpoint) error
+}
+
+// EndpointTable is a Sessions which keeps the endpoints in memory.
+type EndpointTable struct {
+	mtx sync.Mutex
+	eps map[string]Endpoint
+}
+
+func (t *EndpointTable) Open(ep Endpoint) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.eps == nil {
+		t.eps = map[string]Endpoint{}
+	}
+	t.eps[ep.JobID] = ep
+	return nil
+}
+
+func (t *EndpointTable) Close(ep Endpoint) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	delete(t.eps, ep.JobID)
+	return nil
+}
+
+// Endpoints returns the endpoints of the running session jobs,
+// sorted by job ID.
+func (t *EndpointTable) Endpoints() []Endpoint {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	var eps []Endpoint
+	for _, ep := range t.eps {
+		eps = append(eps, ep)
+	}
+	sort.Slice(eps, func(i, j int) bool {
+		return eps[i].JobID < eps[j].JobID
+	})
+	return eps
+}
+
+// Running is called by executors once the job's command is running on host,
+// i.e. once the endpoint of a session job is reachable. Only the first call
+// has an effect.
+func (job *Job) Running(host string) {
+	if job.running != nil {
+		job.running(host)
+		job.running = nil
+	}
+}
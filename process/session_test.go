@@ -0,0 +1,58 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+const sessionTool = `
+class: CommandLineTool
+cwlVersion: v1.0
+hints:
+  SessionRequirement:
+    port: $(inputs.port)
+    path: lab
+inputs:
+  port: int
+outputs: []
+baseCommand: jupyter-lab
+`
+
+func TestSessionEndpoint(t *testing.T) {
+	tool := loadDoc(t, sessionTool).(*cwl.Tool)
+	table := &EndpointTable{}
+	exec := &sessionExecutor{table: table}
+	e := &Engine{Executor: NewSessionExecutor(exec, table)}
+
+	if _, err := e.RunTool(tool, cwl.Values{"port": 8888}); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.during) != 1 {
+		t.Fatalf("expected an endpoint while the job runs, got %v", exec.during)
+	}
+	ep := exec.during[0]
+	if ep.Host != "node1" || ep.Port != 8888 || ep.URL != "http://node1:8888/lab" {
+		t.Errorf("unexpected endpoint %+v", ep)
+	}
+	if eps := table.Endpoints(); len(eps) != 0 {
+		t.Errorf("expected the endpoint to be removed, got %v", eps)
+	}
+
+	// The endpoint is removed when the job fails too.
+	exec.code = 1
+	if _, err := e.RunTool(tool, cwl.Values{"port": 8888}); err == nil {
+		t.Error("expected an error")
+	}
+	if len(exec.during) != 1 || len(table.Endpoints()) != 0 {
+		t.Errorf("unexpected endpoints: %v %v", exec.during, table.Endpoints())
+	}
+}
+
+func TestSessionPort(t *testing.T) {
+	tool := loadDoc(t, sessionTool).(*cwl.Tool)
+	_, err := NewProcess(tool, cwl.Values{"port": -1}, Runtime{}, nil)
+	if err == nil {
+		t.Error("expected an error for an invalid port")
+	}
+}
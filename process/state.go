@@ -0,0 +1,142 @@
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+
+	"github.com/lijiang2014/cwl"
+)
+
+// State is the resumable state of a workflow execution. It records
+// the outputs of every completed job, so that a workflow run with
+// the same State skips the jobs which already completed.
+//
+// Jobs are identified by the path of step IDs leading to them,
+// e.g. "step1", "subworkflow/step2", and scatter jobs are identified
+// by their index, e.g. "step1[3]".
+type State struct {
+	// Inputs is the workflow input object the state was recorded with.
+	Inputs json.RawMessage `json:"inputs,omitempty"`
+	// Jobs maps the ID of each completed job to its outputs.
+	Jobs map[string]cwl.Values `json:"jobs"`
+
+	mtx  sync.Mutex
+	path string
+}
+
+// NewState creates an empty state which is saved to the file at `path`
+// every time a job completes. If `path` is empty, the state is not saved.
+func NewState(path string) *State {
+	return &State{Jobs: map[string]cwl.Values{}, path: path}
+}
+
+// LoadState loads a state previously saved to the file at `path`.
+// Further updates to the state are saved to the same file.
+func LoadState(path string) (*State, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Inputs json.RawMessage                   `json:"inputs"`
+		Jobs   map[string]map[string]interface{} `json:"jobs"`
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, wrap(err, "parsing state file %s", path)
+	}
+
+	s := NewState(path)
+	s.Inputs = data.Inputs
+	for key, outputs := range data.Jobs {
+		vals, err := toValues(outputs)
+		if err != nil {
+			return nil, wrap(err, "loading outputs of job %q", key)
+		}
+		s.Jobs[key] = vals
+	}
+	return s, nil
+}
+
+// checkInputs records the workflow inputs in a new state, or verifies
+// that a loaded state was recorded with the same inputs. Resuming
+// with different inputs would reuse outputs of unrelated jobs.
+func (s *State) checkInputs(inputs cwl.Values) error {
+	if s == nil {
+		return nil
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	b, err := json.Marshal(inputs)
+	if err != nil {
+		return wrap(err, "marshaling workflow inputs")
+	}
+
+	if len(s.Inputs) == 0 {
+		s.Inputs = b
+		return s.save()
+	}
+
+	// Compare in canonical form, since the loaded inputs may have been
+	// formatted differently.
+	var saved, current interface{}
+	if err := json.Unmarshal(s.Inputs, &saved); err != nil {
+		return wrap(err, "parsing saved workflow inputs")
+	}
+	if err := json.Unmarshal(b, &current); err != nil {
+		return wrap(err, "parsing workflow inputs")
+	}
+	x, err := json.Marshal(saved)
+	if err != nil {
+		return wrap(err, "marshaling saved workflow inputs")
+	}
+	y, err := json.Marshal(current)
+	if err != nil {
+		return wrap(err, "marshaling workflow inputs")
+	}
+	if !bytes.Equal(x, y) {
+		return errf("the workflow state was recorded with different inputs")
+	}
+	return nil
+}
+
+// completed returns the outputs of a job, if the job has completed.
+func (s *State) completed(job string) (cwl.Values, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	out, ok := s.Jobs[job]
+	return out, ok
+}
+
+// record marks a job as completed and saves the state.
+func (s *State) record(job string, outputs cwl.Values) error {
+	if s == nil {
+		return nil
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.Jobs[job] = outputs
+	return s.save()
+}
+
+// save writes the state to its file. The caller must hold s.mtx.
+func (s *State) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return wrap(err, "marshaling workflow state")
+	}
+	if err := writeFileAtomic(s.path, b); err != nil {
+		return wrap(err, "saving workflow state")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+func TestResumeWorkflow(t *testing.T) {
+	doc := loadDoc(t, `
+class: Workflow
+cwlVersion: v1.0
+requirements:
+  - class: ScatterFeatureRequirement
+inputs:
+  xs: int[]
+outputs:
+  result:
+    type: int[]
+    outputSource: step1/out
+steps:
+  step1:
+    scatter: n
+    in:
+      n: xs
+    out: [out]
+    run:
+`+indent(incTool))
+
+	dir, err := ioutil.TempDir("", "cwl-state-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	inputs := cwl.Values{"xs": []cwl.Value{1, 2, 3}}
+
+	exec := &countExecutor{fail: 2}
+	e := &Engine{Executor: exec, State: NewState(path)}
+	if _, err := e.Run(doc, inputs); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	state, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Jobs) != 2 {
+		t.Errorf("expected 2 completed jobs, got %v", state.Jobs)
+	}
+
+	exec = &countExecutor{}
+	e = &Engine{Executor: exec, State: state}
+	out, err := e.Run(doc, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exec.runs != 1 {
+		t.Errorf("expected only the failed job to run again, got %d runs", exec.runs)
+	}
+	if s := fmt.Sprint(out["result"]); s != "[2 3 4]" {
+		t.Errorf("expected result [2 3 4], got %s", s)
+	}
+
+	e = &Engine{Executor: exec, State: state}
+	if _, err := e.Run(doc, cwl.Values{"xs": []cwl.Value{4}}); err == nil {
+		t.Error("expected error when resuming with different inputs")
+	}
+}
@@ -0,0 +1,33 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+func TestStdin(t *testing.T) {
+	for _, stdin := range []string{"$(inputs.file1.path)", "$(inputs.file1)"} {
+		doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+inputs:
+  file1: File
+outputs: []
+baseCommand: cat
+stdin: `+stdin+`
+`)
+
+		fs := checksumFS{"/data/hello.txt": ""}
+		proc, err := NewProcess(doc.(*cwl.Tool), cwl.Values{
+			"file1": cwl.File{Location: "/data/hello.txt"},
+		}, Runtime{}, fs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Inputs are staged under "/inputs".
+		if proc.Stdin() != "/inputs/data/hello.txt" {
+			t.Errorf("%s: expected stdin /inputs/data/hello.txt, got %q", stdin, proc.Stdin())
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package process
+
+import (
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/expr"
+)
+
+/*** CWL workflow step input code ***/
+
+// stepInputs builds the input object of a step from the values of its sources,
+// applying linkMerge, pickValue and default. valueFrom is applied separately by
+// evalValueFrom, because the spec requires it to be evaluated after scattering.
+// http://www.commonwl.org/v1.2/Workflow.html#WorkflowStepInput
+func (r *workflowRun) stepInputs(step *cwl.Step) (cwl.Values, error) {
+	stepID := r.localID(step.ID)
+	inputs := cwl.Values{}
+
+	for _, in := range step.In {
+		id := r.stepLocalID(stepID, in.ID)
+
+		var val cwl.Value
+		if len(in.Source) == 1 && in.LinkMerge == "" {
+			val = r.values[r.localID(in.Source[0])]
+		} else if len(in.Source) > 0 {
+			var vals []cwl.Value
+			for _, src := range in.Source {
+				vals = append(vals, r.values[r.localID(src)])
+			}
+			val = linkMerge(vals, in.LinkMerge)
+		}
+		if in.PickValue != "" {
+			var err error
+			if val, err = pickValue(val, in.PickValue); err != nil {
+				return nil, errf("input %q: %s", id, err)
+			}
+		}
+
+		// cwl spec:
+		// "The default value for this parameter to use if either there is no source field,
+		// or the value produced by the source is null."
+		if val == nil {
+			val = normalizeValue(in.Default)
+		}
+		if val != nil {
+			inputs[id] = val
+		}
+	}
+	return inputs, nil
+}
+
+// linkMerge merges the values of multiple sources (or a single source
+// with an explicit link merge method) into a single value.
+// http://www.commonwl.org/v1.0/Workflow.html#WorkflowStepInput
+func linkMerge(vals []cwl.Value, method cwl.LinkMergeMethod) cwl.Value {
+	switch {
+	case len(vals) == 0:
+		return nil
+	case len(vals) == 1 && method == "":
+		return vals[0]
+	}
+
+	out := []cwl.Value{}
+	switch method {
+	case cwl.MergeFlattened:
+		// cwl spec:
+		// "For each input link: if the source parameter is an array, concatenate
+		// array elements to the output; otherwise append the source value
+		// to the output as a single element."
+		for _, val := range vals {
+			if arr, ok := val.([]cwl.Value); ok {
+				out = append(out, arr...)
+			} else {
+				out = append(out, val)
+			}
+		}
+	default:
+		// cwl spec:
+		// "The default link merge method is merge_nested. The input must be an array
+		// consisting of exactly one entry for each input link."
+		out = append(out, vals...)
+	}
+	return out
+}
+
+// pickValue picks the non-null values of a merged source value,
+// i.e. an array with an element per source, or the array value of
+// a single source, e.g. the gathered outputs of a conditional scattered step.
+// http://www.commonwl.org/v1.2/Workflow.html#WorkflowStepInput
+func pickValue(val cwl.Value, method cwl.PickValueMethod) (cwl.Value, error) {
+	vals, ok := val.([]cwl.Value)
+	if !ok {
+		return nil, errf("pickValue requires an array of values, got %#v", val)
+	}
+	var nonNull []cwl.Value
+	for _, v := range vals {
+		if v != nil {
+			nonNull = append(nonNull, v)
+		}
+	}
+
+	switch method {
+	case cwl.FirstNonNull:
+		// cwl spec:
+		// "For the first level of a list input, pick the first non-null element.
+		// The result is a scalar. It is an error if there is no non-null element."
+		if len(nonNull) == 0 {
+			return nil, errf("first_non_null: all source values are null")
+		}
+		return nonNull[0], nil
+
+	case cwl.TheOnlyNonNull:
+		// cwl spec:
+		// "For the first level of a list input, pick the single non-null element.
+		// The result is a scalar. It is an error if there is more than one
+		// non-null element."
+		// A list without non-null elements is an error too.
+		if len(nonNull) != 1 {
+			return nil, errf("the_only_non_null: expected exactly one non-null value, got %d", len(nonNull))
+		}
+		return nonNull[0], nil
+
+	case cwl.AllNonNull:
+		// cwl spec:
+		// "For the first level of a list input, pick all non-null values.
+		// The result is a list, which may be empty."
+		if nonNull == nil {
+			nonNull = []cwl.Value{}
+		}
+		return nonNull, nil
+	}
+	return nil, errf("unknown pickValue method %q", method)
+}
+
+// evalWhen evaluates the "when" condition of a step for a single job's
+// input object, i.e. after scattering and valueFrom, and returns true
+// if the job should run. A skipped job produces null for each output.
+// It's an error if the condition doesn't evaluate to a boolean.
+// http://www.commonwl.org/v1.2/Workflow.html#WorkflowStep
+func (r *workflowRun) evalWhen(step *cwl.Step, inputs cwl.Values) (bool, error) {
+	if step.When == "" {
+		return true, nil
+	}
+	inputsData, err := r.jsInputs(step, inputs)
+	if err != nil {
+		return false, err
+	}
+	res, err := expr.Eval(step.When, r.expressionLibs(), map[string]interface{}{
+		"inputs": inputsData,
+	})
+	if err != nil {
+		return false, errf("failed to evaluate when: %s", err)
+	}
+	run, ok := res.(bool)
+	if !ok {
+		return false, errf("when must evaluate to a boolean, got %#v", res)
+	}
+	return run, nil
+}
+
+// skippedOutputs returns the output object of a skipped step,
+// which is null for each output.
+func (r *workflowRun) skippedOutputs(step *cwl.Step) cwl.Values {
+	stepID := r.localID(step.ID)
+	out := cwl.Values{}
+	for _, o := range step.Out {
+		out[r.stepLocalID(stepID, o.ID)] = nil
+	}
+	return out
+}
+
+// jsInputs converts a step's input object to the "inputs" object
+// of expressions evaluated for the step, i.e. valueFrom and when.
+func (r *workflowRun) jsInputs(step *cwl.Step, inputs cwl.Values) (map[string]interface{}, error) {
+	stepID := r.localID(step.ID)
+	inputsData := map[string]interface{}{}
+	for _, in := range step.In {
+		id := r.stepLocalID(stepID, in.ID)
+		v, err := toJSONMap(inputs[id])
+		if err != nil {
+			return nil, wrap(err, `marshaling "%s" for JS eval`, id)
+		}
+		if v == nil {
+			v = expr.Null
+		}
+		inputsData[id] = v
+	}
+	return inputsData, nil
+}
+
+// evalValueFrom evaluates the valueFrom expressions of a step's inputs
+// for a single job's input object.
+//
+// cwl spec:
+// "The value of inputs in the parameter reference or expression must be
+// the input object to the workflow step after assigning the source values,
+// applying default, and then scattering. The order of evaluating valueFrom
+// among step input parameters is undefined and the result of evaluating
+// valueFrom on a parameter must not be visible to evaluation of valueFrom
+// on other parameters."
+func (r *workflowRun) evalValueFrom(step *cwl.Step, inputs cwl.Values) (cwl.Values, error) {
+	stepID := r.localID(step.ID)
+
+	var hasValueFrom bool
+	for _, in := range step.In {
+		if in.ValueFrom != "" {
+			hasValueFrom = true
+		}
+	}
+	if !hasValueFrom {
+		return inputs, nil
+	}
+
+	inputsData, err := r.jsInputs(step, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	libs := r.expressionLibs()
+	out := copyValues(inputs)
+
+	for _, in := range step.In {
+		if in.ValueFrom == "" {
+			continue
+		}
+		id := r.stepLocalID(stepID, in.ID)
+
+		selfData, err := toJSONMap(inputs[id])
+		if err != nil {
+			return nil, wrap(err, `marshaling "self" for JS eval`)
+		}
+
+		res, err := expr.Eval(in.ValueFrom, libs, map[string]interface{}{
+			"inputs": inputsData,
+			"self":   selfData,
+		})
+		if err != nil {
+			return nil, errf(`input %q: failed to evaluate valueFrom: %s`, id, err)
+		}
+
+		val, err := toValue(res)
+		if err != nil {
+			return nil, errf(`input %q: loading valueFrom result: %s`, id, err)
+		}
+		out[id] = val
+	}
+	return out, nil
+}
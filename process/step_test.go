@@ -0,0 +1,108 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+func TestLinkMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		vals   []cwl.Value
+		method cwl.LinkMergeMethod
+		expect cwl.Value
+	}{
+		{"single", []cwl.Value{"a"}, "", "a"},
+		{"single nested", []cwl.Value{"a"}, cwl.MergeNested, []cwl.Value{"a"}},
+		{"nested", []cwl.Value{[]cwl.Value{"a"}, "b"}, "", []cwl.Value{[]cwl.Value{"a"}, "b"}},
+		{"flattened", []cwl.Value{[]cwl.Value{"a", "b"}, "c"}, cwl.MergeFlattened, []cwl.Value{"a", "b", "c"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := linkMerge(test.vals, test.method)
+			if !reflect.DeepEqual(res, test.expect) {
+				t.Errorf("expected %#v, got %#v", test.expect, res)
+			}
+		})
+	}
+}
+
+const echoTool = `
+class: CommandLineTool
+cwlVersion: v1.0
+baseCommand: "true"
+inputs:
+  first: string
+  echo_in: string
+outputs:
+  echo_out:
+    type: string
+    outputBinding:
+      outputEval: $(inputs.first + " " + inputs.echo_in)
+`
+
+func TestStepValueFrom(t *testing.T) {
+	doc := loadDoc(t, `
+class: Workflow
+cwlVersion: v1.0
+requirements:
+  - class: ScatterFeatureRequirement
+  - class: StepInputExpressionRequirement
+inputs:
+  inp: string[]
+outputs:
+  out:
+    type: string[]
+    outputSource: step1/echo_out
+steps:
+  step1:
+    scatter: echo_in
+    in:
+      echo_in:
+        source: inp
+        valueFrom: $(self + "!")
+      first:
+        source: inp
+        valueFrom: $(self[0])
+    out: [echo_out]
+    run:
+`+indent(echoTool))
+
+	e := &Engine{Executor: nopExecutor{}}
+	out, err := e.Run(doc, cwl.Values{"inp": []cwl.Value{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []cwl.Value{"a a!", "a b!"}
+	if !reflect.DeepEqual(out["out"], expect) {
+		t.Errorf("expected %#v, got %#v", expect, out["out"])
+	}
+}
+
+func TestStepValueFromRequirement(t *testing.T) {
+	doc := loadDoc(t, `
+class: Workflow
+cwlVersion: v1.0
+inputs:
+  inp: string
+outputs: []
+steps:
+  step1:
+    in:
+      first: inp
+      echo_in:
+        default: foo
+        valueFrom: $(self)
+    out: [echo_out]
+    run:
+`+indent(echoTool))
+
+	e := &Engine{Executor: nopExecutor{}}
+	_, err := e.Run(doc, cwl.Values{"inp": "a"})
+	if err == nil {
+		t.Error("expected error for valueFrom without StepInputExpressionRequirement")
+	}
+}
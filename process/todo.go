@@ -7,7 +7,6 @@ TODO
 - good framework for e2e tests with lots of coverage
 - really good debug logging, with the goal of clearly explaining to a **user**
   what is going on when a job fails at any step, especially input/output binding.
-- success/failure codes and relationship to CLI cmd
 - solid expression parser (regexp misses edge cases and escaping)
 - type check cwl.output.json
 - filesystem multiplexing based on location
@@ -17,17 +16,12 @@ TODO
 - carefully check document json/yaml marshaling
 - input/output record type handling
 - executor backends
-- directory type
 - $include and $import
 - test unrecognized fields are ignored (possibly with warning)
 - optional checksum calculation for filesystems
-- resource requests
-- initial work dir
 - time limit on JS evaluation
 
 workflow execution:
-- basics
-- caching
 
 server + API:
 
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"github.com/lijiang2014/cwl"
 	"github.com/kr/pretty"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 )
 
@@ -35,3 +38,71 @@ func debug(args ...interface{}) {
 	}
 	fmt.Fprintf(os.Stderr, strings.Join(fmts, " ")+"\n", formatters...)
 }
+
+// copyValues returns a shallow copy of an input object, so that the
+// copy may be modified (e.g. by setting defaults) without affecting the original.
+func copyValues(vals cwl.Values) cwl.Values {
+	out := cwl.Values{}
+	for k, v := range vals {
+		out[k] = v
+	}
+	return out
+}
+
+// normalizeValue converts a value produced by output binding or expression
+// evaluation (e.g. []interface{}, map[string]interface{}) into the types produced
+// by the input loader ([]cwl.Value, map[string]cwl.Value), so that outputs
+// of one process can be bound as inputs of another.
+func normalizeValue(v cwl.Value) cwl.Value {
+	switch z := v.(type) {
+	case nil:
+		return nil
+	case cwl.File, cwl.Directory:
+		return z
+	case cwl.Values:
+		out := map[string]cwl.Value{}
+		for k, x := range z {
+			out[k] = normalizeValue(x)
+		}
+		return out
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		out := make([]cwl.Value, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			out[i] = normalizeValue(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		out := map[string]cwl.Value{}
+		for _, k := range rv.MapKeys() {
+			out[k.String()] = normalizeValue(rv.MapIndex(k).Interface())
+		}
+		return out
+	}
+	return v
+}
+
+// writeFileAtomic writes a file via a temporary file which is then renamed,
+// so a crash while writing never leaves a truncated file behind.
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
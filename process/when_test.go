@@ -0,0 +1,184 @@
+package process
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+// branchWorkflow runs one of two branches depending on "trim",
+// and picks the output of the branch which ran.
+var branchWorkflow = `
+cwlVersion: v1.2
+class: Workflow
+requirements:
+  StepInputExpressionRequirement: {}
+  MultipleInputFeatureRequirement: {}
+inputs:
+  n: int
+  trim: boolean
+outputs:
+  out:
+    type: int
+    outputSource: [trimmed/out, untrimmed/out]
+    pickValue: the_only_non_null
+  first:
+    type: int
+    outputSource: [trimmed/out, untrimmed/out]
+    pickValue: first_non_null
+  all:
+    type: int[]
+    outputSource: [trimmed/out, untrimmed/out]
+    pickValue: all_non_null
+  next:
+    type: int
+    outputSource: next/out
+steps:
+  next:
+    in:
+      n:
+        source: [trimmed/out, untrimmed/out]
+        pickValue: first_non_null
+    out: [out]
+    run:
+      ` + indent(incTool) + `
+  trimmed:
+    when: $(inputs.trim)
+    in:
+      n: n
+      trim: trim
+    out: [out]
+    run:
+      ` + indent(incTool) + `
+  untrimmed:
+    when: $(!inputs.trim)
+    in:
+      n: {source: n, valueFrom: $(self * 10)}
+      trim: trim
+    out: [out]
+    run:
+      ` + indent(incTool) + `
+`
+
+func TestWhen(t *testing.T) {
+	wf := loadDoc(t, branchWorkflow).(*cwl.Workflow)
+	e := &Engine{Executor: nopExecutor{}}
+
+	out, err := e.RunWorkflow(wf, cwl.Values{"n": 1, "trim": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := cwl.Values{"out": int32(2), "first": int32(2), "all": []cwl.Value{int32(2)}, "next": int32(3)}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %#v, got %#v", expected, out)
+	}
+
+	// The condition sees the input object after valueFrom.
+	out, err = e.RunWorkflow(wf, cwl.Values{"n": 1, "trim": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = cwl.Values{"out": int32(11), "first": int32(11), "all": []cwl.Value{int32(11)}, "next": int32(12)}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %#v, got %#v", expected, out)
+	}
+}
+
+func TestWhenScatter(t *testing.T) {
+	wf := loadDoc(t, `
+cwlVersion: v1.2
+class: Workflow
+requirements:
+  ScatterFeatureRequirement: {}
+inputs:
+  ns: int[]
+outputs:
+  out:
+    type: int?[]
+    outputSource: step/out
+  picked:
+    type: int[]
+    outputSource: step/out
+    pickValue: all_non_null
+steps:
+  step:
+    when: $(inputs.n != 2)
+    scatter: n
+    in:
+      n: ns
+    out: [out]
+    run:
+      `+indent(incTool)+`
+`).(*cwl.Workflow)
+
+	e := &Engine{Executor: nopExecutor{}}
+	out, err := e.RunWorkflow(wf, cwl.Values{"ns": []cwl.Value{1, 2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := cwl.Values{
+		"out":    []cwl.Value{int32(2), nil, int32(4)},
+		"picked": []cwl.Value{int32(2), int32(4)},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %#v, got %#v", expected, out)
+	}
+}
+
+func TestWhenErrors(t *testing.T) {
+	wf := loadDoc(t, branchWorkflow).(*cwl.Workflow)
+	e := &Engine{Executor: nopExecutor{}}
+
+	// Both branches are skipped.
+	both := *wf
+	both.Steps = append([]cwl.Step(nil), wf.Steps...)
+	both.Steps[2].When = "$(inputs.trim)"
+	_, err := e.RunWorkflow(&both, cwl.Values{"n": 1, "trim": false})
+	if err == nil || !strings.Contains(err.Error(), "non_null") {
+		t.Errorf("expected a pickValue error, got %v", err)
+	}
+
+	// Both branches run.
+	both.Steps[2].When = "$(true)"
+	_, err = e.RunWorkflow(&both, cwl.Values{"n": 1, "trim": true})
+	if err == nil || !strings.Contains(err.Error(), "exactly one non-null value, got 2") {
+		t.Errorf("expected a the_only_non_null error, got %v", err)
+	}
+
+	both.Steps[2].When = "$(inputs.n)"
+	_, err = e.RunWorkflow(&both, cwl.Values{"n": 1, "trim": true})
+	if err == nil || !strings.Contains(err.Error(), "boolean") {
+		t.Errorf("expected an error for a non-boolean condition, got %v", err)
+	}
+}
+
+func TestPickValue(t *testing.T) {
+	tests := []struct {
+		method   cwl.PickValueMethod
+		val      cwl.Value
+		expected cwl.Value
+		err      bool
+	}{
+		{cwl.FirstNonNull, []cwl.Value{nil, 1, 2}, 1, false},
+		{cwl.FirstNonNull, []cwl.Value{nil, nil}, nil, true},
+		{cwl.TheOnlyNonNull, []cwl.Value{nil, 1, nil}, 1, false},
+		{cwl.TheOnlyNonNull, []cwl.Value{1, 2}, nil, true},
+		{cwl.TheOnlyNonNull, []cwl.Value{nil}, nil, true},
+		{cwl.AllNonNull, []cwl.Value{nil, 1, nil, 2}, []cwl.Value{1, 2}, false},
+		{cwl.AllNonNull, []cwl.Value{nil}, []cwl.Value{}, false},
+		{cwl.AllNonNull, 1, nil, true},
+		{"bogus", []cwl.Value{1}, nil, true},
+	}
+	for _, test := range tests {
+		got, err := pickValue(test.val, test.method)
+		if (err != nil) != test.err {
+			t.Errorf("%s %v: unexpected error %v", test.method, test.val, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s %v: expected %#v, got %#v", test.method, test.val, test.expected, got)
+		}
+	}
+}
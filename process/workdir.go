@@ -0,0 +1,292 @@
+package process
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/lijiang2014/cwl"
+)
+
+/*** CWL InitialWorkDirRequirement code ***/
+
+// WorkDirEntry is a file or directory which must be staged into the working
+// directory of a process before the command runs, as listed by
+// InitialWorkDirRequirement.
+type WorkDirEntry struct {
+	// Path is the path of the entry in the working directory,
+	// i.e. under Runtime.Outdir.
+	Path string
+	// Location of the file or directory to stage at Path.
+	// Empty for files created from Contents and for new directories.
+	Location string
+	// Contents of a file created by the listing, if Location is empty.
+	Contents string
+	// Directory is true if the entry is a directory.
+	Directory bool
+	// Writable entries must be copied into the working directory,
+	// so the command may modify them without affecting the original.
+	// Other entries may be linked or mounted read-only.
+	Writable bool
+}
+
+// WorkDir returns the files and directories which must be staged into
+// the working directory before the command runs, in listing order.
+// Entries of a directory follow the entry of the directory itself.
+func (process *Process) WorkDir() []WorkDirEntry {
+	return process.workdir
+}
+
+func (process *Process) evalWorkDirRequirement(listing []cwl.InitialWorkDirListing) error {
+	for i, item := range listing {
+		var entries []WorkDirEntry
+		var err error
+
+		switch z := item.(type) {
+		case cwl.Dirent:
+			entries, err = process.evalDirent(z)
+
+		case cwl.Expression:
+			// cwl spec:
+			// "If the value is an expression that evaluates to a File object,
+			// this indicates the referenced file should be added to the
+			// designated output directory prior to executing the tool."
+			var val interface{}
+			val, err = process.eval(z, nil)
+			if err == nil {
+				entries, err = process.workDirEntries(val, "", "", false)
+			}
+
+		case cwl.File, cwl.Directory:
+			entries, err = process.workDirEntries(z, "", "", false)
+
+		default:
+			err = errf("unknown listing item type %T", item)
+		}
+
+		if err != nil {
+			return errf("listing item %d: %s", i, err)
+		}
+		process.workdir = append(process.workdir, entries...)
+	}
+
+	// Inputs staged in the working directory must refer to the staged copy,
+	// e.g. in "$(inputs.file.path)" and on the command line.
+	for _, entry := range process.workdir {
+		if entry.Location != "" {
+			process.relocateInput(entry.Location, entry.Path)
+		}
+	}
+	return nil
+}
+
+// evalDirent evaluates the entry and entryname expressions of a Dirent.
+func (process *Process) evalDirent(d cwl.Dirent) ([]WorkDirEntry, error) {
+	var name string
+	if d.Entryname != "" {
+		val, err := process.eval(d.Entryname, nil)
+		if err != nil {
+			return nil, errf(`failed to evaluate entryname "%s": %s`, d.Entryname, err)
+		}
+		str, ok := val.(string)
+		if !ok {
+			return nil, errf(`entryname must evaluate to a string, got "%v"`, val)
+		}
+		name = str
+	}
+
+	val, err := process.eval(d.Entry, nil)
+	if err != nil {
+		return nil, errf(`failed to evaluate entry "%s": %s`, d.Entry, err)
+	}
+
+	// cwl spec:
+	// "If the value is a string literal or an expression which evaluates
+	// to a string, a new file must be created with the string as the file contents."
+	if str, ok := val.(string); ok {
+		if name == "" {
+			return nil, errf("entryname is required for an entry with string contents")
+		}
+		path, err := process.workDirPath("", name)
+		if err != nil {
+			return nil, err
+		}
+		return []WorkDirEntry{{Path: path, Contents: str, Writable: d.Writable}}, nil
+	}
+	return process.workDirEntries(val, "", name, d.Writable)
+}
+
+// workDirEntries converts a File, a Directory, or an array of those into
+// entries staged into the directory `dir`, relative to the working directory.
+// If `name` is non-empty, the entry is renamed.
+func (process *Process) workDirEntries(val interface{}, dir, name string, writable bool) ([]WorkDirEntry, error) {
+	switch z := val.(type) {
+	case nil:
+		return nil, nil
+
+	case []cwl.Value:
+		if name != "" {
+			return nil, errf("entryname can't be used with an array of entries")
+		}
+		var entries []WorkDirEntry
+		for _, x := range z {
+			e, err := process.workDirEntries(x, dir, "", writable)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, e...)
+		}
+		return entries, nil
+
+	case []interface{}, map[string]interface{}:
+		// Expression results are JSON-like data.
+		x, err := toValue(z)
+		if err != nil {
+			return nil, err
+		}
+		switch x.(type) {
+		case []cwl.Value, cwl.File, cwl.Directory:
+			return process.workDirEntries(x, dir, name, writable)
+		}
+		return nil, errf("expected a File or Directory, got %v", z)
+
+	case *cwl.File:
+		return process.workDirEntries(*z, dir, name, writable)
+
+	case cwl.File:
+		if name == "" {
+			name = z.Basename
+		}
+
+		// A file literal, e.g. {class: File, basename: a.txt, contents: "..."}
+		if z.Contents != "" && z.Location == "" {
+			if name == "" {
+				return nil, errf("basename is required for a file literal in the working directory")
+			}
+			path, err := process.workDirPath(dir, name)
+			if err != nil {
+				return nil, err
+			}
+			return []WorkDirEntry{{Path: path, Contents: z.Contents, Writable: writable}}, nil
+		}
+
+		// Files which weren't resolved by input binding,
+		// e.g. a File listed in the document.
+		if z.Path == "" {
+			f, err := process.resolveFile(z, false)
+			if err != nil {
+				return nil, err
+			}
+			z = f
+		}
+		if name == "" {
+			name = filepath.Base(z.Path)
+		}
+
+		path, err := process.workDirPath(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		entries := []WorkDirEntry{{Path: path, Location: z.Location, Writable: writable}}
+
+		// Secondary files are staged alongside the primary file.
+		for _, sec := range z.SecondaryFiles {
+			e, err := process.workDirEntries(sec, filepath.Dir(filepath.Join(dir, name)), "", writable)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, e...)
+		}
+		return entries, nil
+
+	case *cwl.Directory:
+		return process.workDirEntries(*z, dir, name, writable)
+
+	case cwl.Directory:
+		if name == "" {
+			name = z.Basename
+		}
+		if name == "" {
+			name = filepath.Base(z.Location)
+		}
+		if name == "" || name == "." || name == "/" {
+			return nil, errf("basename is required for a directory literal in the working directory")
+		}
+
+		path, err := process.workDirPath(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		if z.Location != "" {
+			return []WorkDirEntry{{Path: path, Location: z.Location, Directory: true, Writable: writable}}, nil
+		}
+
+		// A directory literal, e.g. {class: Directory, basename: d, listing: [...]},
+		// is created with its listing.
+		entries := []WorkDirEntry{{Path: path, Directory: true, Writable: writable}}
+		for _, item := range z.Listing {
+			e, err := process.workDirEntries(item, filepath.Join(dir, name), "", writable)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, e...)
+		}
+		return entries, nil
+	}
+	return nil, errf("expected a File, Directory or an array of those, got %v", val)
+}
+
+// workDirPath returns the path of an entry in the working directory.
+// Entries may not be staged outside the working directory.
+func (process *Process) workDirPath(dir, name string) (string, error) {
+	rel := filepath.Clean(filepath.Join(dir, name))
+	if filepath.IsAbs(name) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", errf("entry %q is outside the working directory", name)
+	}
+	return filepath.Join(process.runtime.Outdir, rel), nil
+}
+
+// relocateInput updates the path of an input file or directory,
+// and of every copy of it in other bindings, after it has been
+// staged in the working directory.
+func (process *Process) relocateInput(loc, path string) {
+	var walk func(bs []*Binding)
+	walk = func(bs []*Binding) {
+		for _, b := range bs {
+			b.Value = relocate(b.Value, loc, path)
+			walk(b.nested)
+		}
+	}
+	walk(process.bindings)
+}
+
+func relocate(v cwl.Value, loc, path string) cwl.Value {
+	switch z := v.(type) {
+	case cwl.File:
+		if z.Location == loc {
+			z.Path = path
+			z.Basename = filepath.Base(path)
+			z.Dirname = filepath.Dir(path)
+			z.Nameroot, z.Nameext = splitname(z.Basename)
+		}
+		return z
+	case cwl.Directory:
+		if z.Location == loc {
+			z = setDirPath(z, path)
+			z.Basename = filepath.Base(path)
+		}
+		return z
+	case []cwl.Value:
+		out := make([]cwl.Value, len(z))
+		for i, x := range z {
+			out[i] = relocate(x, loc, path)
+		}
+		return out
+	case map[string]cwl.Value:
+		out := map[string]cwl.Value{}
+		for k, x := range z {
+			out[k] = relocate(x, loc, path)
+		}
+		return out
+	}
+	return v
+}
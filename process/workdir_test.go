@@ -0,0 +1,125 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/lijiang2014/cwl"
+)
+
+func TestInitialWorkDir(t *testing.T) {
+	doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  InlineJavascriptRequirement: {}
+  InitialWorkDirRequirement:
+    listing:
+      - entryname: $(inputs.name)
+        entry: $(inputs.src)
+      - entryname: script.sh
+        entry: |
+          echo hello
+      - entry: "$({class: 'Directory', basename: 'nested', listing: [{class: 'Directory', basename: 'empty', listing: []}, {class: 'File', basename: 'a.txt', contents: 'a'}]})"
+        writable: true
+      - $(inputs.others)
+inputs:
+  src: File
+  name: string
+  others: File[]
+outputs: []
+baseCommand: cat
+arguments: [$(inputs.src.path)]
+`)
+
+	fs := checksumFS{"/data/src.txt": "", "/data/b.txt": "", "/data/c.txt": ""}
+	proc, err := NewProcess(doc.(*cwl.Tool), cwl.Values{
+		"src":  cwl.File{Location: "/data/src.txt"},
+		"name": "renamed.txt",
+		"others": []cwl.Value{
+			cwl.File{Location: "/data/b.txt"},
+			cwl.File{Location: "/data/c.txt"},
+		},
+	}, Runtime{Outdir: "/out"}, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []WorkDirEntry{
+		{Path: "/out/renamed.txt", Location: "file:///data/src.txt"},
+		{Path: "/out/script.sh", Contents: "echo hello\n"},
+		{Path: "/out/nested", Directory: true, Writable: true},
+		{Path: "/out/nested/empty", Directory: true, Writable: true},
+		{Path: "/out/nested/a.txt", Contents: "a", Writable: true},
+		{Path: "/out/b.txt", Location: "file:///data/b.txt"},
+		{Path: "/out/c.txt", Location: "file:///data/c.txt"},
+	}
+	if !reflect.DeepEqual(proc.WorkDir(), expect) {
+		t.Error("unexpected work dir entries")
+		for _, d := range pretty.Diff(proc.WorkDir(), expect) {
+			t.Log(d)
+		}
+	}
+
+	// The staged input refers to the staged copy.
+	cmd, err := proc.Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cmd, []string{"cat", "/out/renamed.txt"}) {
+		t.Errorf("unexpected command %v", cmd)
+	}
+}
+
+func TestInitialWorkDirOutside(t *testing.T) {
+	doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  InitialWorkDirRequirement:
+    listing:
+      - entryname: ../escape.txt
+        entry: foo
+inputs: []
+outputs: []
+baseCommand: "true"
+`)
+
+	_, err := NewProcess(doc.(*cwl.Tool), cwl.Values{}, Runtime{Outdir: "/out"}, nil)
+	if err == nil {
+		t.Error("expected error for an entry outside the working directory")
+	}
+}
+
+func TestInitialWorkDirListingExpression(t *testing.T) {
+	doc := loadDoc(t, `
+class: CommandLineTool
+cwlVersion: v1.0
+requirements:
+  InitialWorkDirRequirement:
+    listing: $(inputs.indir.listing)
+inputs:
+  indir: Directory
+outputs: []
+baseCommand: "true"
+`)
+
+	fs := checksumFS{"/data/a.txt": ""}
+	proc, err := NewProcess(doc.(*cwl.Tool), cwl.Values{
+		"indir": cwl.Directory{
+			Basename: "indir",
+			Listing: []cwl.FileDir{
+				cwl.File{Location: "/data/a.txt"},
+			},
+		},
+	}, Runtime{Outdir: "/out"}, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []WorkDirEntry{{Path: "/out/a.txt", Location: "file:///data/a.txt"}}
+	if !reflect.DeepEqual(proc.WorkDir(), expect) {
+		t.Errorf("expected %#v, got %#v", expect, proc.WorkDir())
+	}
+}
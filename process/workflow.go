@@ -1,130 +1,575 @@
 package process
 
 import (
-  "fmt"
-  "github.com/lijiang2014/cwl"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/rs/xid"
 )
 
-type scope struct {
-  prefix string
-  links map[string][]string
+// ToolExecutor executes the command line of a bound Process.
+// On success, it returns the Filesystem holding the files produced
+// by the command, which is used to bind the tool's outputs.
+type ToolExecutor interface {
+	Execute(proc *Process) (Filesystem, error)
 }
-func (n scope) child(prefix string) scope {
-  return scope{prefix: n.prefix + "/" + prefix, links: n.links}
+
+// Engine executes CWL documents. CommandLineTools are bound via NewProcess
+// and executed by Executor. Workflows are executed step by step, where each
+// step runs as soon as all of its inputs are available.
+type Engine struct {
+	Runtime    Runtime
+	Filesystem Filesystem
+	Executor   ToolExecutor
+	// Parallel is the maximum number of workflow jobs run concurrently.
+	// Zero means no limit.
+	Parallel int
+	// State, if non-nil, records the outputs of completed workflow jobs.
+	// Jobs already recorded in State are not run again, which allows
+	// resuming an interrupted workflow run.
+	State *State
+	// Cache, if non-nil, stores the outputs of CommandLineTool jobs.
+	// Jobs identical to a cached job return the cached outputs
+	// instead of running.
+	Cache Cache
+	// Retries is the number of times a CommandLineTool job is run again
+	// after failing with a temporaryFail status.
+	Retries int
+	// JobDirs, if set, is a host directory in which each CommandLineTool job
+	// gets its own runtime outdir, tmpdir and staged inputs, e.g. for executors
+	// running commands on the host. Otherwise every job uses Runtime as is.
+	JobDirs string
 }
-func (n scope) link(name string, val string) {
-  key := n.key(name)
-  n.links[key] = append(n.links[key], val)
+
+// Run executes a CWL document with the given input values
+// and returns the document's output object.
+func (e *Engine) Run(doc cwl.Document, inputs cwl.Values) (cwl.Values, error) {
+	switch z := doc.(type) {
+	case *cwl.Tool:
+		return e.RunTool(z, inputs)
+	case *cwl.ExpressionTool:
+		return e.RunExpressionTool(z, inputs)
+	case *cwl.Workflow:
+		return e.RunWorkflow(z, inputs)
+	case *cwl.Operation:
+		return nil, errf(`operation "%s" has no implementation to run`, z.ID)
+	case cwl.Graph:
+		entry, err := z.Entry("")
+		if err != nil {
+			return nil, err
+		}
+		return e.Run(entry, inputs)
+	}
+	return nil, errf(`unknown document type "%s"`, doc.Doctype())
 }
-func (n scope) key(name string) string {
-  return n.prefix + "/" + name
+
+// RunTool binds a CommandLineTool to its inputs, executes the resulting
+// command and binds the tool's outputs.
+func (e *Engine) RunTool(tool *cwl.Tool, inputs cwl.Values) (cwl.Values, error) {
+	if e.Executor == nil {
+		return nil, errf("no executor configured")
+	}
+
+	rt := e.Runtime
+	if e.JobDirs != "" {
+		dir := filepath.Join(e.JobDirs, xid.New().String())
+		rt.RootHost = dir
+		rt.Outdir = filepath.Join(dir, "outdir")
+		rt.Tmpdir = filepath.Join(dir, "tmp")
+	}
+
+	proc, err := NewProcess(tool, copyValues(inputs), rt, e.Filesystem)
+	if err != nil {
+		return nil, err
+	}
+
+	var key string
+	if e.Cache != nil {
+		key, err = CacheKey(proc)
+		if err != nil {
+			return nil, wrap(err, "computing cache key")
+		}
+		out, ok, err := e.Cache.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return out, nil
+		}
+	}
+
+	fs, err := e.Executor.Execute(proc)
+	for retry := 0; retry < e.Retries && StatusOf(err) == TemporaryFail; retry++ {
+		fs, err = e.Executor.Execute(proc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out, err := proc.Outputs(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Cache != nil {
+		if err := e.Cache.Put(key, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
 }
 
+// RunExpressionTool binds an ExpressionTool to its inputs
+// and evaluates the tool's expression.
+func (e *Engine) RunExpressionTool(tool *cwl.ExpressionTool, inputs cwl.Values) (cwl.Values, error) {
+	proc, err := NewExpressionProcess(tool, copyValues(inputs), e.Runtime, e.Filesystem)
+	if err != nil {
+		return nil, err
+	}
+	return proc.Outputs()
+}
 
-// DebugWorkflow is a temporary placeholder for workflow processing code.
-func DebugWorkflow(wf *cwl.Workflow, vals cwl.Values) {
+// RunWorkflow executes all the steps of a workflow and returns
+// the workflow output object.
+func (e *Engine) RunWorkflow(wf *cwl.Workflow, inputs cwl.Values) (cwl.Values, error) {
+	// Resolve SchemaDefRequirement types in a copy of the workflow,
+	// which catches undefined and circular references before any step runs.
+	resolved := *wf
+	if err := resolved.ResolveSchemaDefs(); err != nil {
+		return nil, errf("resolving SchemaDefRequirement types: %s", err)
+	}
+	run := &workflowRun{engine: e, wf: &resolved}
+	run.reqs = cwl.MergeRequirements(wf.Requirements)
+	run.hints = cwl.MergeHints(run.reqs, wf.Hints)
+	if e.Parallel > 0 {
+		run.jobs = make(chan struct{}, e.Parallel)
+	}
+	if err := e.State.checkInputs(inputs); err != nil {
+		return nil, err
+	}
+	return run.run(inputs)
+}
 
-  root := scope{prefix: "", links: map[string][]string{}}
+// workflowRun holds the state of a single workflow execution.
+type workflowRun struct {
+	engine *Engine
+	wf     *cwl.Workflow
+	// values maps workflow input IDs and step output IDs ("step/output")
+	// to their values. A key is present once its value is available.
+	values map[string]cwl.Value
+	// jobs limits the number of concurrently running jobs, if non-nil.
+	// It is shared by subworkflows.
+	jobs chan struct{}
+	// the requirements and hints in effect for the workflow,
+	// including those inherited from parent workflows and steps.
+	reqs  []cwl.Requirement
+	hints []cwl.Requirement
+	// scope is the ID of the job running this workflow, e.g. "step1/"
+	// for a subworkflow. It prefixes the IDs of the workflow's jobs.
+	scope string
+}
 
-  inputs := root.child("inputs")
-  for k, _ := range vals {
-    root.link(k, inputs.key(k))
-  }
+// run executes the workflow with the given input object
+// and returns the workflow output object.
+func (r *workflowRun) run(inputs cwl.Values) (cwl.Values, error) {
+	r.values = map[string]cwl.Value{}
+	for _, in := range r.wf.Inputs {
+		id := r.localID(in.ID)
+		val, ok := inputs[id]
+		if !ok || val == nil {
+			val = in.Default
+		}
+		r.values[id] = normalizeValue(val)
+	}
 
-  exports := linkWorkflow(wf, root)
-  outputs := root.child("outputs")
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	if err := r.runSteps(); err != nil {
+		return nil, err
+	}
+	return r.outputs()
+}
 
-  for _, out := range wf.Outputs {
-    outputs.link(out.ID, exports.key(out.ID))
-  }
+// subworkflow creates the run of a workflow embedded in a step of this workflow.
+// The subworkflow has its own namespace of values and inherits the requirements
+// and hints of the step and this workflow. Its SchemaDefRequirement types
+// are resolved in a copy of `wf`, as in RunWorkflow.
+func (r *workflowRun) subworkflow(step *cwl.Step, wf *cwl.Workflow, job string) (*workflowRun, error) {
+	resolved := *wf
+	if err := resolved.ResolveSchemaDefs(); err != nil {
+		return nil, errf("resolving SchemaDefRequirement types: %s", err)
+	}
+	reqs, hints := step.ResolveRequirements(r.reqs, r.hints)
+	return &workflowRun{
+		engine: r.engine,
+		wf:     &resolved,
+		jobs:   r.jobs,
+		reqs:   reqs,
+		hints:  hints,
+		scope:  job + "/",
+	}, nil
+}
 
-  walk(root.links, []string{"/outputs/count_output"})
+// expressionLibs returns the InlineJavascriptRequirement.expressionLib
+// in effect for this workflow.
+func (r *workflowRun) expressionLibs() []string {
+	reqs := append(append([]cwl.Requirement{}, r.reqs...), r.hints...)
+	for _, req := range reqs {
+		if z, ok := req.(cwl.InlineJavascriptRequirement); ok {
+			return z.ExpressionLib
+		}
+	}
+	return nil
+}
 
+// requires returns true if a requirement of the same type as `x` is
+// in effect for the given step of this workflow.
+func (r *workflowRun) requires(step *cwl.Step, x cwl.Requirement) bool {
+	reqs := cwl.MergeRequirements(step.Requirements, r.reqs)
+	reqs = append(reqs, cwl.MergeHints(reqs, step.Hints, r.hints)...)
+	for _, req := range reqs {
+		if reflect.TypeOf(req) == reflect.TypeOf(x) {
+			return true
+		}
+	}
+	return false
 }
 
-func walk(links map[string][]string, keys []string) {
-  for _, key := range keys {
-    fmt.Println(key)
-    walk(links, links[key])
-  }
+// stepResult is sent by a step goroutine when the step finishes.
+type stepResult struct {
+	step    *cwl.Step
+	outputs cwl.Values
+	err     error
 }
 
-func linkWorkflow(wf *cwl.Workflow, parent scope) scope {
+// runSteps runs every step of the workflow. Steps run concurrently
+// as soon as all of their sources are available.
+func (r *workflowRun) runSteps() error {
+	pending := make([]*cwl.Step, 0, len(r.wf.Steps))
+	for i := range r.wf.Steps {
+		pending = append(pending, &r.wf.Steps[i])
+	}
+
+	results := make(chan stepResult, len(pending))
+	running := 0
 
-  internal := parent.child("workflow")
-  for _, in := range wf.Inputs {
-    internal.link(in.ID, parent.key(in.ID))
-  }
+	// fail waits for the steps still running before returning `err`,
+	// so that no job runs or records its outputs after the workflow
+	// has failed. Their results are discarded.
+	fail := func(err error) error {
+		for ; running > 0; running-- {
+			<-results
+		}
+		return err
+	}
 
-  for _, step := range wf.Steps {
-    stepScope := internal.child("step/" + step.ID)
+	for {
+		var waiting []*cwl.Step
+		for _, step := range pending {
+			if !r.ready(step) {
+				waiting = append(waiting, step)
+				continue
+			}
 
-    for _, in := range step.In {
-      for _, src := range in.Source {
-        stepScope.link(in.ID, internal.key(src))
-      }
-    }
+			inputs, err := r.stepInputs(step)
+			if err != nil {
+				return fail(errf("step %q: %s", step.ID, err))
+			}
 
-    stepExports := linkDoc(step.Run, stepScope)
+			running++
+			go func(step *cwl.Step, inputs cwl.Values) {
+				out, err := r.runStep(step, inputs)
+				results <- stepResult{step, out, err}
+			}(step, inputs)
+		}
+		pending = waiting
 
-    for _, out := range step.Out {
-      id := step.ID + "/" + out.ID
-      internal.link(id, stepExports.key(out.ID))
-    }
-  }
+		if running == 0 {
+			if len(pending) != 0 {
+				return errf("step %q can never run: its sources are unavailable", pending[0].ID)
+			}
+			return nil
+		}
 
-  exports := internal.child("exports")
-  for _, out := range wf.Outputs {
-    for _, src := range out.OutputSource {
-      exports.link(out.ID, internal.key(src))
-    }
-  }
+		res := <-results
+		running--
+		if res.err != nil {
+			return fail(wrapStatus(res.err, "step %q", res.step.ID))
+		}
 
-  return exports
+		stepID := r.localID(res.step.ID)
+		for _, out := range res.step.Out {
+			id := r.stepLocalID(stepID, out.ID)
+			r.values[stepID+"/"+id] = normalizeValue(res.outputs[id])
+		}
+	}
 }
 
-func linkTool(in []cwl.CommandInput, out []cwl.CommandOutput, parent scope) scope {
-  internal := parent.child("tool")
-  for _, in := range in {
-    internal.link(in.ID, parent.key(in.ID))
-    internal.link("toolexec", internal.key(in.ID))
-  }
-  exports := internal.child("exports")
-  for _, out := range out {
-    exports.link(out.ID, internal.key("toolexec"))
-  }
-  return exports
+// runStep executes a step. If the step is scattered, one job is run
+// per scatter element and the job outputs are gathered into arrays.
+func (r *workflowRun) runStep(step *cwl.Step, inputs cwl.Values) (cwl.Values, error) {
+	stepID := r.localID(step.ID)
+	jobID := r.scope + stepID
+
+	if len(step.Scatter) == 0 {
+		inputs, err := r.evalValueFrom(step, inputs)
+		if err != nil {
+			return nil, err
+		}
+		run, err := r.evalWhen(step, inputs)
+		if err != nil || !run {
+			return r.skippedOutputs(step), err
+		}
+		return r.runJob(step, inputs, jobID)
+	}
+
+	if out, ok := r.engine.State.completed(jobID); ok {
+		return out, nil
+	}
+
+	var params []string
+	for _, p := range step.Scatter {
+		params = append(params, r.stepLocalID(stepID, p))
+	}
+
+	jobs, shape, err := scatterJobs(inputs, params, step.ScatterMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	// cwl spec: valueFrom is evaluated after scattering,
+	// so "self" is the scattered value of each job.
+	for i, job := range jobs {
+		jobs[i], err = r.evalValueFrom(step, job)
+		if err != nil {
+			return nil, errf("scatter job %d: %s", i, err)
+		}
+	}
+
+	// Scatter jobs are independent, so run them all concurrently.
+	// Jobs skipped by the step's condition have null outputs.
+	results := make([]cwl.Values, len(jobs))
+	errs := make(chan error, len(jobs))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		run, err := r.evalWhen(step, job)
+		if err != nil {
+			return nil, errf("scatter job %d: %s", i, err)
+		}
+		if !run {
+			results[i] = r.skippedOutputs(step)
+			continue
+		}
+		wg.Add(1)
+		go func(i int, job cwl.Values) {
+			defer wg.Done()
+			out, err := r.runJob(step, job, fmt.Sprintf("%s%s[%d]", r.scope, stepID, i))
+			if err != nil {
+				errs <- wrapStatus(err, "scatter job %d", i)
+				return
+			}
+			results[i] = out
+		}(i, job)
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	outputs := cwl.Values{}
+	for _, out := range step.Out {
+		id := r.stepLocalID(stepID, out.ID)
+		vals := make([]cwl.Value, len(results))
+		for i, res := range results {
+			vals[i] = normalizeValue(res[id])
+		}
+		outputs[id] = gatherOutputs(vals, shape)
+	}
+	if err := r.engine.State.record(jobID, outputs); err != nil {
+		return nil, err
+	}
+	return outputs, nil
 }
 
-func linkDoc(doc cwl.Document, parent scope) scope {
-  switch z := doc.(type) {
-  case *cwl.Workflow:
-    return linkWorkflow(z, parent)
-  case *cwl.Tool:
-    return linkTool(z.Inputs, z.Outputs, parent)
-  case *cwl.ExpressionTool:
-    return linkTool(z.Inputs, z.Outputs, parent)
-  }
-  return scope{}
+// runJob executes the document referenced by the step's "run" field
+// with a single input object. `job` identifies the job in the workflow
+// State: if the job already completed, its recorded outputs are returned.
+func (r *workflowRun) runJob(step *cwl.Step, inputs cwl.Values, job string) (cwl.Values, error) {
+	if out, ok := r.engine.State.completed(job); ok {
+		return out, nil
+	}
+
+	out, err := r.execJob(step, inputs, job)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.engine.State.record(job, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// execJob executes a single job of a step.
+func (r *workflowRun) execJob(step *cwl.Step, inputs cwl.Values, job string) (cwl.Values, error) {
+	// Subworkflows don't occupy a job slot, otherwise they could
+	// block forever waiting for their own steps to get a slot.
+	if wf, ok := step.Run.(*cwl.Workflow); ok {
+		sub, err := r.subworkflow(step, wf, job)
+		if err != nil {
+			return nil, err
+		}
+		return sub.run(inputs)
+	}
+
+	if r.jobs != nil {
+		r.jobs <- struct{}{}
+		defer func() { <-r.jobs }()
+	}
+
+	// Run a copy of the tool with the requirements and hints inherited
+	// from the step and workflow.
+	reqs, hints := step.ResolveRequirements(r.reqs, r.hints)
+
+	switch z := step.Run.(type) {
+	case *cwl.Tool:
+		tool := *z
+		tool.Requirements, tool.Hints = reqs, hints
+		return r.engine.RunTool(&tool, inputs)
+	case *cwl.ExpressionTool:
+		tool := *z
+		tool.Requirements, tool.Hints = reqs, hints
+		return r.engine.RunExpressionTool(&tool, inputs)
+	case nil:
+		return nil, errf("missing run document")
+	}
+	return nil, errf(`running "%s" documents from a step is not supported`, step.Run.Doctype())
+}
+
+// ready returns true if all the sources of the step's inputs are available.
+func (r *workflowRun) ready(step *cwl.Step) bool {
+	for _, in := range step.In {
+		for _, src := range in.Source {
+			if _, ok := r.values[r.localID(src)]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// outputs builds the workflow output object from the workflow output sources,
+// applying linkMerge and pickValue.
+func (r *workflowRun) outputs() (cwl.Values, error) {
+	outputs := cwl.Values{}
+	for _, out := range r.wf.Outputs {
+		id := r.localID(out.ID)
+
+		var vals []cwl.Value
+		for _, src := range out.OutputSource {
+			vals = append(vals, r.values[r.localID(src)])
+		}
+		val := linkMerge(vals, out.LinkMerge)
+		if out.PickValue != "" {
+			var err error
+			if val, err = pickValue(val, out.PickValue); err != nil {
+				return nil, errf("output %q: %s", id, err)
+			}
+		}
+		outputs[id] = val
+	}
+	return outputs, nil
+}
+
+// validate checks that every source referenced by a step input or workflow output
+// is either a workflow input or an output declared by a step.
+func (r *workflowRun) validate() error {
+	known := map[string]bool{}
+	for _, in := range r.wf.Inputs {
+		known[r.localID(in.ID)] = true
+	}
+
+	steps := map[string]bool{}
+	for _, step := range r.wf.Steps {
+		stepID := r.localID(step.ID)
+		if steps[stepID] {
+			return errf("duplicate step ID %q", stepID)
+		}
+		steps[stepID] = true
+
+		for _, out := range step.Out {
+			known[stepID+"/"+r.stepLocalID(stepID, out.ID)] = true
+		}
+	}
+
+	for _, step := range r.wf.Steps {
+		stepID := r.localID(step.ID)
+		inputs := map[string]bool{}
+		for _, in := range step.In {
+			inputs[r.stepLocalID(stepID, in.ID)] = true
+			for _, src := range in.Source {
+				if !known[r.localID(src)] {
+					return errf("step %q: input %q: unknown source %q", step.ID, in.ID, src)
+				}
+			}
+		}
+
+		for _, p := range step.Scatter {
+			if !inputs[r.stepLocalID(stepID, p)] {
+				return errf("step %q: scatter parameter %q is not a step input", step.ID, p)
+			}
+		}
+		if len(step.Scatter) > 1 && step.ScatterMethod == "" {
+			return errf("step %q: scatterMethod is required when scattering over multiple inputs", step.ID)
+		}
+
+		for _, in := range step.In {
+			if in.ValueFrom != "" && !r.requires(&step, cwl.StepInputExpressionRequirement{}) {
+				return errf("step %q: input %q: valueFrom requires StepInputExpressionRequirement", step.ID, in.ID)
+			}
+		}
+
+		if _, ok := step.Run.(*cwl.Workflow); ok && !r.requires(&step, cwl.SubworkflowFeatureRequirement{}) {
+			return errf("step %q: running a workflow as a step requires SubworkflowFeatureRequirement", step.ID)
+		}
+	}
+
+	for _, out := range r.wf.Outputs {
+		for _, src := range out.OutputSource {
+			if !known[r.localID(src)] {
+				return errf("output %q: unknown source %q", out.ID, src)
+			}
+		}
+	}
+	return nil
+}
+
+// localID strips the fragment marker and workflow ID prefix from an ID,
+// e.g. "#main/step1/out" becomes "step1/out".
+func (r *workflowRun) localID(id string) string {
+	id = strings.TrimPrefix(id, "#")
+	wfID := strings.TrimPrefix(r.wf.ID, "#")
+	if wfID != "" {
+		id = strings.TrimPrefix(id, wfID+"/")
+	}
+	return id
+}
+
+// stepLocalID strips the step ID prefix from a step input or output ID,
+// e.g. "step1/out" becomes "out".
+func (r *workflowRun) stepLocalID(stepID, id string) string {
+	return strings.TrimPrefix(r.localID(id), stepID+"/")
 }
 
 /*
 TODO goals
 
-- validate that links are correct, not missing any links, etc
-- have (un)marshal-able workflow state
 - validate value bindings, mid workflow
 - resolve inputs to step in nested workflow, mid workflow
-- encode links between steps directly, without intermediate layers
-
-
-implementation thoughts:
-- major element of name translation over many layers
-- end result is link between two Process objects and/or
-  Step objects.
-- possibly want global Start/End steps, or maybe only End;
-  End.Done() is true when the workflow is done. need to
-  also have link between workflow outputs and last steps
 - want to query value of value by name at any layer?
   e.g. query for workflow.step0.count_output mid workflow
 */
@@ -0,0 +1,232 @@
+package process
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lijiang2014/cwl"
+)
+
+func TestRunWorkflow(t *testing.T) {
+	doc := loadDoc(t, `
+class: Workflow
+cwlVersion: v1.0
+inputs:
+  x: int
+outputs:
+  result:
+    type: int
+    outputSource: step2/out
+steps:
+  step2:
+    in:
+      n: step1/out
+    out: [out]
+    run:
+`+indent(incTool)+`
+  step1:
+    in:
+      n: x
+    out: [out]
+    run:
+`+indent(incTool))
+
+	e := &Engine{Executor: nopExecutor{}}
+	out, err := e.Run(doc, cwl.Values{"x": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["result"] != int32(3) {
+		t.Errorf("expected result 3, got %#v", out["result"])
+	}
+}
+
+func TestRunWorkflowUnknownSource(t *testing.T) {
+	doc := loadDoc(t, `
+class: Workflow
+cwlVersion: v1.0
+inputs:
+  x: int
+outputs:
+  result:
+    type: int
+    outputSource: step1/missing
+steps:
+  step1:
+    in:
+      n: x
+    out: [out]
+    run:
+`+indent(incTool))
+
+	e := &Engine{Executor: nopExecutor{}}
+	_, err := e.Run(doc, cwl.Values{"x": 1})
+	if err == nil {
+		t.Fatal("expected error for unknown output source")
+	}
+}
+
+func TestRunWorkflowFailureWaitsForSteps(t *testing.T) {
+	doc := loadDoc(t, `
+class: Workflow
+cwlVersion: v1.0
+inputs:
+  x: int
+  y: int
+outputs: []
+steps:
+  fails:
+    in:
+      n: x
+    out: [out]
+    run:
+`+indent(incTool)+`
+  slow:
+    in:
+      n: y
+    out: [out]
+    run:
+`+indent(incTool))
+
+	exec := &slowExecutor{fail: 1, delay: 50 * time.Millisecond}
+	e := &Engine{Executor: exec}
+	if _, err := e.Run(doc, cwl.Values{"x": 1, "y": 2}); err == nil {
+		t.Fatal("expected error from failed step")
+	}
+	if done := atomic.LoadInt32(&exec.done); done != 1 {
+		t.Errorf("expected the running step to finish before the workflow fails, %d finished", done)
+	}
+}
+
+func TestRunSubworkflow(t *testing.T) {
+	sub := `
+class: Workflow
+cwlVersion: v1.0
+inputs:
+  n: int
+outputs:
+  out:
+    type: int
+    outputSource: inc/out
+steps:
+  inc:
+    in:
+      n: n
+    out: [out]
+    run:
+` + indent(incTool)
+
+	doc := loadDoc(t, `
+class: Workflow
+cwlVersion: v1.0
+requirements:
+  - class: SubworkflowFeatureRequirement
+inputs:
+  x: int
+outputs:
+  result:
+    type: int
+    outputSource: inc/out
+steps:
+  sub:
+    in:
+      n: x
+    out: [out]
+    run:
+`+indent(sub)+`
+  inc:
+    in:
+      n: sub/out
+    out: [out]
+    run:
+`+indent(incTool))
+
+	e := &Engine{Executor: nopExecutor{}, Parallel: 1}
+	out, err := e.Run(doc, cwl.Values{"x": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["result"] != int32(3) {
+		t.Errorf("expected result 3, got %#v", out["result"])
+	}
+}
+
+func TestRunSubworkflowRequirement(t *testing.T) {
+	sub := `
+class: Workflow
+cwlVersion: v1.0
+inputs: []
+outputs: []
+steps: []
+`
+	doc := loadDoc(t, `
+class: Workflow
+cwlVersion: v1.0
+inputs: []
+outputs: []
+steps:
+  sub:
+    in: []
+    out: []
+    run:
+`+indent(sub))
+
+	e := &Engine{Executor: nopExecutor{}}
+	if _, err := e.Run(doc, cwl.Values{}); err == nil {
+		t.Error("expected error for subworkflow without SubworkflowFeatureRequirement")
+	}
+}
+
+func TestRequirementInheritance(t *testing.T) {
+	tool := `
+class: CommandLineTool
+cwlVersion: v1.0
+baseCommand: "true"
+hints:
+  EnvVarRequirement:
+    envDef:
+      FROM: tool-hint
+inputs: []
+outputs: []
+`
+	doc := loadDoc(t, `
+class: Workflow
+cwlVersion: v1.0
+requirements:
+  EnvVarRequirement:
+    envDef:
+      FROM: workflow
+inputs: []
+outputs: []
+steps:
+  inherit:
+    in: []
+    out: []
+    run:
+`+indent(tool)+`
+  override:
+    requirements:
+      EnvVarRequirement:
+        envDef:
+          FROM: step
+    in: []
+    out: []
+    run:
+`+indent(tool))
+
+	exec := envExecutor{make(chan map[string]string, 2)}
+	e := &Engine{Executor: exec, Parallel: 1}
+	if _, err := e.Run(doc, cwl.Values{}); err != nil {
+		t.Fatal(err)
+	}
+	close(exec.env)
+
+	got := map[string]bool{}
+	for env := range exec.env {
+		got[env["FROM"]] = true
+	}
+	if !got["workflow"] || !got["step"] || len(got) != 2 {
+		t.Errorf("unexpected inherited requirements: %v", got)
+	}
+}
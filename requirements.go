@@ -1,7 +1,12 @@
 package cwl
 
+// UnknownRequirement is a requirement or hint of a class the loader
+// doesn't model, e.g. a vendor extension. Fields holds the fields it was
+// loaded with, except "class", with the YAML types of unquoted scalars,
+// so that it can be written back as it was written.
 type UnknownRequirement struct {
-	Name string
+	Name   string
+	Fields Values
 }
 
 type DockerRequirement struct {
@@ -28,7 +33,7 @@ type SchedulerRequirement struct {
 // ParallelRequirement 用来描述作业执行的并行环境
 // Runtime 会根据需要 进行 srun/mpirun 的 包装
 type ParallelRequirement struct {
-	MpiEnabled  bool `json:"writeable,omitempty"`
+	MpiEnabled  bool `json:"mpiEnabled,omitempty"`
 }
 
 // KubernetesRequirement 用来描述 Kubernetes deployment
@@ -38,6 +43,14 @@ type KubernetesRequirement struct {
 	Proxies []Expression `json:"proxies,omitempty"`
 }
 
+// SessionRequirement describes the endpoint of an interactive session tool,
+// e.g. a notebook or remote desktop, which is reachable while the job runs.
+type SessionRequirement struct {
+	Port     Expression `json:"port,omitempty"`
+	Protocol string     `json:"protocol,omitempty"`
+	Path     Expression `json:"path,omitempty"`
+}
+
 
 type ResourceRequirement struct {
 	CoresMin  Expression `json:"coresMin,omitempty"`
@@ -80,20 +93,55 @@ type SoftwarePackage struct {
 	Specs   []string `json:"specs,omitempty"`
 }
 
-type InitialWorkDirListing struct{
-	Entry     Expression `json:"entry,omitempty"`
-	Entryname Expression `json:"entryname,omitempty"`
+// InitialWorkDirListing is an item of an InitialWorkDirRequirement listing,
+// one of Dirent, Expression, File or Directory.
+//
+// An Expression item must evaluate to a File, a Directory,
+// or an array of those.
+type InitialWorkDirListing interface {
+	initialWorkDirListing()
 }
 
 type InitialWorkDirRequirement struct {
-	// TODO the most difficult union type
+	// A listing given as a single expression is loaded
+	// as a listing with a single Expression item.
 	Listing []InitialWorkDirListing `json:"listing,omitempty"`
 }
 
 type Dirent struct {
 	Entry     Expression `json:"entry,omitempty"`
 	Entryname Expression `json:"entryname,omitempty"`
-	Writable  bool       `json:"writeable,omitempty"`
+	Writable  bool       `json:"writable,omitempty"`
+}
+
+// LoadListingRequirement sets the default loadListing
+// of the Directory inputs and outputs of a tool.
+type LoadListingRequirement struct {
+	LoadListing LoadListing `json:"loadListing,omitempty"`
+}
+
+// ToolTimeLimit sets the maximum number of seconds a tool may run,
+// where 0 means no limit. Added in v1.1.
+type ToolTimeLimit struct {
+	TimeLimit Expression `json:"timelimit,omitempty"`
+}
+
+// NetworkAccess sets whether a tool may access the network.
+// Added in v1.1.
+type NetworkAccess struct {
+	NetworkAccess Expression `json:"networkAccess,omitempty"`
+}
+
+// WorkReuse sets whether the results of a tool may be reused
+// from a previous run. Added in v1.1.
+type WorkReuse struct {
+	EnableReuse Expression `json:"enableReuse,omitempty"`
+}
+
+// InplaceUpdateRequirement allows a tool to modify writable
+// InitialWorkDirRequirement files in place. Added in v1.1.
+type InplaceUpdateRequirement struct {
+	InplaceUpdate bool `json:"inplaceUpdate,omitempty"`
 }
 
 type SubworkflowFeatureRequirement struct {
@@ -52,8 +52,29 @@ func (l *loader) MappingToRequirement(n node) (Requirement, error) {
 	return l.loadReqByName(class, n)
 }
 
-func (l *loader) SeqToInitialWorkDirListing(n node) (InitialWorkDirListing, error) {
-	return InitialWorkDirListing{}, nil
+// ScalarToInitialWorkDirListingSlice loads a listing given as a single expression.
+func (l *loader) ScalarToInitialWorkDirListingSlice(n node) ([]InitialWorkDirListing, error) {
+	return []InitialWorkDirListing{Expression(n.Value)}, nil
+}
+
+func (l *loader) ScalarToInitialWorkDirListing(n node) (InitialWorkDirListing, error) {
+	return Expression(n.Value), nil
+}
+
+func (l *loader) MappingToInitialWorkDirListing(n node) (InitialWorkDirListing, error) {
+	switch strings.ToLower(findKey(n, "class")) {
+	case "file":
+		f := File{}
+		err := l.load(n, &f)
+		return f, err
+	case "directory":
+		d := Directory{}
+		err := l.load(n, &d)
+		return d, err
+	}
+	d := Dirent{}
+	err := l.load(n, &d)
+	return d, err
 }
 
 func (l *loader) loadReqByName(name string, n node) (Requirement, error) {
@@ -90,6 +111,42 @@ func (l *loader) loadReqByName(name string, n node) (Requirement, error) {
 		r := InitialWorkDirRequirement{}
 		err := l.load(n, &r)
 		return r, err
+	case "loadlistingrequirement":
+		r := LoadListingRequirement{}
+		err := l.load(n, &r)
+		return r, err
+	case "tooltimelimit":
+		r := ToolTimeLimit{}
+		err := l.load(n, &r)
+		return r, err
+	case "networkaccess":
+		r := NetworkAccess{}
+		err := l.load(n, &r)
+		return r, err
+	case "workreuse":
+		r := WorkReuse{}
+		err := l.load(n, &r)
+		return r, err
+	case "inplaceupdaterequirement":
+		r := InplaceUpdateRequirement{}
+		err := l.load(n, &r)
+		return r, err
+	case "schedulerrequirement":
+		r := SchedulerRequirement{}
+		err := l.load(n, &r)
+		return r, err
+	case "parallelrequirement":
+		r := ParallelRequirement{}
+		err := l.load(n, &r)
+		return r, err
+	case "kubernetesrequirement":
+		r := KubernetesRequirement{}
+		err := l.load(n, &r)
+		return r, err
+	case "sessionrequirement":
+		r := SessionRequirement{}
+		err := l.load(n, &r)
+		return r, err
 	case "subworkflowfeaturerequirement":
 		return SubworkflowFeatureRequirement{}, nil
 	case "scatterfeaturerequirement":
@@ -99,7 +156,16 @@ func (l *loader) loadReqByName(name string, n node) (Requirement, error) {
 	case "stepinputexpressionrequirement":
 		return StepInputExpressionRequirement{}, nil
 	}
-	return UnknownRequirement{Name: name}, nil
+	r := UnknownRequirement{Name: name}
+	if n.Kind == yamlast.MappingNode {
+		fields, err := l.yamlValue(n)
+		if err != nil {
+			return nil, err
+		}
+		r.Fields = fields.(map[string]Value)
+		delete(r.Fields, "class")
+	}
+	return r, nil
 	// TODO logging
 	//return nil, fmt.Errorf("unknown requirement name: %s", name)
 }
@@ -0,0 +1,273 @@
+package cwl
+
+import (
+	"strings"
+)
+
+// ResolveSchemaDefs replaces references to SchemaDefRequirement types
+// in the tool's input and output types, including record fields and
+// array items, with the types they refer to.
+//
+// The tool's Inputs and Outputs are replaced, not modified in place,
+// so a shallow copy of a tool may be resolved while the original is in use.
+func (t *Tool) ResolveSchemaDefs() error {
+	defs, _ := t.RequiresSchemaDef()
+	r := newSchemaResolver(defs)
+
+	inputs := make([]CommandInput, len(t.Inputs))
+	for i, in := range t.Inputs {
+		types, err := r.inputTypes(in.Type)
+		if err != nil {
+			return errf("input %q: %s", in.ID, err)
+		}
+		in.Type = types
+		inputs[i] = in
+	}
+
+	outputs := make([]CommandOutput, len(t.Outputs))
+	for i, out := range t.Outputs {
+		types, err := r.outputTypes(out.Type)
+		if err != nil {
+			return errf("output %q: %s", out.ID, err)
+		}
+		out.Type = types
+		outputs[i] = out
+	}
+
+	t.Inputs, t.Outputs = inputs, outputs
+	return nil
+}
+
+// ResolveSchemaDefs replaces references to SchemaDefRequirement types
+// in the workflow's input and output types. See Tool.ResolveSchemaDefs.
+func (wf *Workflow) ResolveSchemaDefs() error {
+	defs, _ := wf.RequiresSchemaDef()
+	r := newSchemaResolver(defs)
+
+	inputs := make([]WorkflowInput, len(wf.Inputs))
+	for i, in := range wf.Inputs {
+		types, err := r.inputTypes(in.Type)
+		if err != nil {
+			return errf("input %q: %s", in.ID, err)
+		}
+		in.Type = types
+		inputs[i] = in
+	}
+
+	outputs := make([]WorkflowOutput, len(wf.Outputs))
+	for i, out := range wf.Outputs {
+		types, err := r.outputTypes(out.Type)
+		if err != nil {
+			return errf("output %q: %s", out.ID, err)
+		}
+		out.Type = types
+		outputs[i] = out
+	}
+
+	wf.Inputs, wf.Outputs = inputs, outputs
+	return nil
+}
+
+func (wf *Workflow) RequiresSchemaDef() (*SchemaDefRequirement, bool) {
+	reqs := append([]Requirement{}, wf.Requirements...)
+	reqs = append(reqs, wf.Hints...)
+	for _, req := range reqs {
+		if r, ok := req.(SchemaDefRequirement); ok {
+			return &r, true
+		}
+	}
+	return nil, false
+}
+
+// schemaResolver resolves type references by name.
+type schemaResolver struct {
+	byName map[string]SchemaDef
+	// path is the chain of references being resolved,
+	// which is used to detect circular references.
+	path []string
+}
+
+func newSchemaResolver(defs *SchemaDefRequirement) *schemaResolver {
+	r := &schemaResolver{byName: map[string]SchemaDef{}}
+	if defs != nil {
+		for _, def := range defs.Types {
+			r.byName[schemaName(def.Name)] = def
+		}
+	}
+	return r
+}
+
+// schemaName returns the name of a schema def or type reference
+// without its document and namespace, e.g. "types.yml#HelloType"
+// and "#HelloType" both become "HelloType".
+func schemaName(name string) string {
+	if i := strings.LastIndex(name, "#"); i != -1 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// lookup finds the schema def a reference refers to.
+func (r *schemaResolver) lookup(ref TypeRef) (SchemaDef, error) {
+	name := schemaName(ref.Name)
+	def, ok := r.byName[name]
+	if !ok {
+		return def, errf(`no schema def named "%s"`, ref.Name)
+	}
+	for _, p := range r.path {
+		if p == name {
+			chain := strings.Join(append(r.path, name), " -> ")
+			return def, errf(`circular reference in schema def "%s": %s`, name, chain)
+		}
+	}
+	return def, nil
+}
+
+func (r *schemaResolver) inputTypes(types []InputType) ([]InputType, error) {
+	if types == nil {
+		return nil, nil
+	}
+	out := make([]InputType, len(types))
+	for i, t := range types {
+		x, err := r.inputType(t)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = x
+	}
+	return out, nil
+}
+
+func (r *schemaResolver) inputType(t InputType) (InputType, error) {
+	switch z := t.(type) {
+
+	case TypeRef:
+		def, err := r.lookup(z)
+		if err != nil {
+			return nil, err
+		}
+		it, ok := def.Type.(InputType)
+		if !ok {
+			return nil, errf(`schema def "%s" is not an input type`, def.Name)
+		}
+		// A schema def may itself refer to other schema defs.
+		r.path = append(r.path, schemaName(z.Name))
+		defer func() { r.path = r.path[:len(r.path)-1] }()
+		return r.inputType(it)
+
+	case InputRecord:
+		fields := make([]InputField, len(z.Fields))
+		for i, f := range z.Fields {
+			types, err := r.inputTypes(f.Type)
+			if err != nil {
+				return nil, errf("field %q: %s", f.Name, err)
+			}
+			f.Type = types
+			fields[i] = f
+		}
+		z.Fields = fields
+		return z, nil
+
+	case InputArray:
+		items, err := r.inputTypes(z.Items)
+		if err != nil {
+			return nil, err
+		}
+		z.Items = items
+		return z, nil
+	}
+	return t, nil
+}
+
+func (r *schemaResolver) outputTypes(types []OutputType) ([]OutputType, error) {
+	if types == nil {
+		return nil, nil
+	}
+	out := make([]OutputType, len(types))
+	for i, t := range types {
+		x, err := r.outputType(t)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = x
+	}
+	return out, nil
+}
+
+func (r *schemaResolver) outputType(t OutputType) (OutputType, error) {
+	switch z := t.(type) {
+
+	case TypeRef:
+		def, err := r.lookup(z)
+		if err != nil {
+			return nil, err
+		}
+		// Schema defs are loaded as input types, which have the same
+		// structure as output types, minus the bindings.
+		ot, ok := toOutputType(def.Type)
+		if !ok {
+			return nil, errf(`schema def "%s" is not an output type`, def.Name)
+		}
+		r.path = append(r.path, schemaName(z.Name))
+		defer func() { r.path = r.path[:len(r.path)-1] }()
+		return r.outputType(ot)
+
+	case OutputRecord:
+		fields := make([]OutputField, len(z.Fields))
+		for i, f := range z.Fields {
+			types, err := r.outputTypes(f.Type)
+			if err != nil {
+				return nil, errf("field %q: %s", f.Name, err)
+			}
+			f.Type = types
+			fields[i] = f
+		}
+		z.Fields = fields
+		return z, nil
+
+	case OutputArray:
+		items, err := r.outputTypes(z.Items)
+		if err != nil {
+			return nil, err
+		}
+		z.Items = items
+		return z, nil
+	}
+	return t, nil
+}
+
+// toOutputType converts a schema def type to the equivalent output type.
+func toOutputType(t cwltype) (OutputType, bool) {
+	switch z := t.(type) {
+	case InputRecord:
+		rec := OutputRecord{Label: z.Label}
+		for _, f := range z.Fields {
+			var types []OutputType
+			for _, ft := range f.Type {
+				ot, ok := toOutputType(ft)
+				if !ok {
+					return nil, false
+				}
+				types = append(types, ot)
+			}
+			rec.Fields = append(rec.Fields, OutputField{Name: f.Name, Doc: f.Doc, Type: types})
+		}
+		return rec, true
+
+	case InputArray:
+		arr := OutputArray{Label: z.Label}
+		for _, it := range z.Items {
+			ot, ok := toOutputType(it)
+			if !ok {
+				return nil, false
+			}
+			arr.Items = append(arr.Items, ot)
+		}
+		return arr, true
+
+	case InputEnum:
+		return OutputEnum{Label: z.Label, Symbols: z.Symbols}, true
+	}
+	ot, ok := t.(OutputType)
+	return ot, ok
+}
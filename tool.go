@@ -20,8 +20,8 @@ type Tool struct {
 	Stdout Expression `json:"stdout,omitempty"`
 
 	SuccessCodes       []int `json:"successCodes,omitempty"`
-	TemporaryFailCodes []int `json:",omitempty"`
-	PermanentFailCodes []int `json:",omitempty"`
+	TemporaryFailCodes []int `json:"temporaryFailCodes,omitempty"`
+	PermanentFailCodes []int `json:"permanentFailCodes,omitempty"`
 }
 
 type CommandInput struct {
@@ -33,8 +33,9 @@ type CommandInput struct {
 
 	Type []InputType `json:"type,omitempty"`
 
-	SecondaryFiles []Expression `json:"secondaryFiles,omitempty"`
-	Format         []Expression `json:"format,omitempty"`
+	SecondaryFiles []SecondaryFile `json:"secondaryFiles,omitempty"`
+	Format         []Expression    `json:"format,omitempty"`
+	LoadListing    LoadListing     `json:"loadListing,omitempty"`
 
 	InputBinding *CommandLineBinding `json:"inputBinding,omitempty"`
 }
@@ -47,8 +48,8 @@ type CommandOutput struct {
 
 	Type []OutputType `json:"type,omitempty"`
 
-	SecondaryFiles []Expression `json:"secondaryFiles,omitempty"`
-	Format         []Expression `json:"format,omitempty"`
+	SecondaryFiles []SecondaryFile `json:"secondaryFiles,omitempty"`
+	Format         []Expression    `json:"format,omitempty"`
 
 	OutputBinding *CommandOutputBinding `json:"outputBinding,omitempty"`
 }
@@ -66,5 +67,6 @@ type CommandLineBinding struct {
 type CommandOutputBinding struct {
 	Glob         []Expression `json:"glob,omitempty"`
 	LoadContents bool         `json:"loadContents,omitempty"`
+	LoadListing  LoadListing  `json:"loadListing,omitempty"`
 	OutputEval   Expression   `json:"outputEval,omitempty"`
 }
@@ -0,0 +1,384 @@
+package cwl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Upgrade rewrites a document, and the documents inlined in it,
+// to a later CWL version, e.g. "v1.2". The document isn't modified.
+//
+// Besides setting cwlVersion, upgrading a v1.0 document keeps its behavior
+// where later versions changed the defaults:
+//   - tools and workflows with Directory inputs or outputs get a
+//     LoadListingRequirement with deep_listing, since v1.1 loads no listing
+//     by default.
+//   - CommandLineTools get a NetworkAccess requirement allowing network
+//     access, since v1.1 denies it by default.
+//   - secondaryFiles patterns get the structured form with required: true,
+//     since v1.1 doesn't require the secondary files of outputs by default.
+//
+// Upgrade also returns a report of the constructs it couldn't migrate,
+// which need to be migrated by hand.
+func Upgrade(doc Document, version string) (Document, []string, error) {
+	if versionIndex(version) <= 0 {
+		return nil, nil, errf(`can't upgrade to cwlVersion "%s"`, version)
+	}
+	u := upgrader{version: version, done: map[Document]Document{}}
+	up, err := u.upgrade(doc, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return up, u.report, nil
+}
+
+type upgrader struct {
+	version string
+	report  []string
+	// done maps the documents already upgraded to their upgrades,
+	// since the documents of a graph may be run by several steps.
+	done map[Document]Document
+}
+
+func (u *upgrader) reportf(where, msg string, args ...interface{}) {
+	msg = fmt.Sprintf(msg, args...)
+	if where != "" {
+		msg = where + ": " + msg
+	}
+	u.report = append(u.report, msg)
+}
+
+// from returns the version a document is upgraded from. Documents without
+// a version are assumed to be v1.0.
+func (u *upgrader) from(where, v string) (string, error) {
+	if v == "" {
+		u.reportf(where, "no cwlVersion, upgrading from v1.0")
+		v = "v1.0"
+	}
+	if versionIndex(v) < 0 {
+		return "", errf(`%s: unsupported cwlVersion "%s"`, where, v)
+	}
+	if versionIndex(v) > versionIndex(u.version) {
+		return "", errf(`%s: can't downgrade cwlVersion "%s" to "%s"`, where, v, u.version)
+	}
+	return normalizeVersion(v), nil
+}
+
+func (u *upgrader) upgrade(doc Document, where string) (Document, error) {
+	switch doc.(type) {
+	case *Tool, *ExpressionTool, *Operation, *Workflow:
+		if up, ok := u.done[doc]; ok {
+			return up, nil
+		}
+		up, err := u.upgradeDoc(doc, where)
+		if err != nil {
+			return nil, err
+		}
+		u.done[doc] = up
+		return up, nil
+	}
+	return u.upgradeDoc(doc, where)
+}
+
+func (u *upgrader) upgradeDoc(doc Document, where string) (Document, error) {
+	switch z := doc.(type) {
+	case *Tool:
+		where = docWhere(where, z.ID, "CommandLineTool")
+		from, err := u.from(where, z.CWLVersion)
+		if err != nil {
+			return nil, err
+		}
+		t := *z
+		t.CWLVersion = u.version
+		if from == "v1.0" {
+			t.Inputs = append([]CommandInput(nil), z.Inputs...)
+			for i := range t.Inputs {
+				t.Inputs[i].SecondaryFiles = requireSecondaryFiles(t.Inputs[i].SecondaryFiles)
+			}
+			t.Outputs = append([]CommandOutput(nil), z.Outputs...)
+			for i := range t.Outputs {
+				t.Outputs[i].SecondaryFiles = requireSecondaryFiles(t.Outputs[i].SecondaryFiles)
+			}
+			if hasDirectory(t.Inputs, t.Outputs) {
+				t.Requirements = deepListing(t.Requirements, t.Hints)
+			}
+			t.Requirements = networkAccess(t.Requirements, t.Hints)
+		}
+		u.requirements(where, t.Requirements, t.Hints)
+		return &t, nil
+
+	case *ExpressionTool:
+		where = docWhere(where, z.ID, "ExpressionTool")
+		from, err := u.from(where, z.CWLVersion)
+		if err != nil {
+			return nil, err
+		}
+		t := *z
+		t.CWLVersion = u.version
+		if from == "v1.0" {
+			t.Inputs = append([]CommandInput(nil), z.Inputs...)
+			for i := range t.Inputs {
+				t.Inputs[i].SecondaryFiles = requireSecondaryFiles(t.Inputs[i].SecondaryFiles)
+			}
+			t.Outputs = append([]CommandOutput(nil), z.Outputs...)
+			for i := range t.Outputs {
+				t.Outputs[i].SecondaryFiles = requireSecondaryFiles(t.Outputs[i].SecondaryFiles)
+			}
+			if hasDirectory(t.Inputs, t.Outputs) {
+				t.Requirements = deepListing(t.Requirements, t.Hints)
+			}
+		}
+		u.requirements(where, t.Requirements, t.Hints)
+		return &t, nil
+
+	case *Operation:
+		where = docWhere(where, z.ID, "Operation")
+		if _, err := u.from(where, z.CWLVersion); err != nil {
+			return nil, err
+		}
+		op := *z
+		op.CWLVersion = u.version
+		return &op, nil
+
+	case *Workflow:
+		where = docWhere(where, z.ID, "Workflow")
+		from, err := u.from(where, z.CWLVersion)
+		if err != nil {
+			return nil, err
+		}
+		wf := *z
+		wf.CWLVersion = u.version
+		if from == "v1.0" {
+			wf.Inputs = append([]WorkflowInput(nil), z.Inputs...)
+			for i := range wf.Inputs {
+				wf.Inputs[i].SecondaryFiles = requireSecondaryFiles(wf.Inputs[i].SecondaryFiles)
+			}
+			wf.Outputs = append([]WorkflowOutput(nil), z.Outputs...)
+			for i := range wf.Outputs {
+				wf.Outputs[i].SecondaryFiles = requireSecondaryFiles(wf.Outputs[i].SecondaryFiles)
+			}
+			if workflowHasDirectory(wf.Inputs, wf.Outputs) {
+				wf.Requirements = deepListing(wf.Requirements, wf.Hints)
+			}
+		}
+		u.requirements(where, wf.Requirements, wf.Hints)
+
+		wf.Steps = append([]Step(nil), z.Steps...)
+		for i := range wf.Steps {
+			step := &wf.Steps[i]
+			stepWhere := fmt.Sprintf(`%s step "%s"`, where, localID(z.ID, step.ID))
+			u.requirements(stepWhere, step.Requirements, step.Hints)
+			if step.Run == nil {
+				continue
+			}
+			run, err := u.upgrade(step.Run, stepWhere)
+			if err != nil {
+				return nil, err
+			}
+			step.Run = run
+		}
+		return &wf, nil
+
+	case Graph:
+		if _, err := u.from(where, z.CWLVersion); err != nil {
+			return nil, err
+		}
+		g := Graph{CWLVersion: u.version}
+		ids := map[Document]string{}
+		for _, doc := range z.Docs {
+			up, err := u.upgrade(doc, where)
+			if err != nil {
+				return nil, err
+			}
+			g.Docs = append(g.Docs, up)
+			ids[up] = docID(up)
+		}
+		// Steps refer to the documents of the graph by ID,
+		// rather than embedding them.
+		for _, doc := range g.Docs {
+			wf, ok := doc.(*Workflow)
+			if !ok {
+				continue
+			}
+			for i := range wf.Steps {
+				if id, ok := ids[wf.Steps[i].Run]; ok {
+					wf.Steps[i].Run = DocumentRef{Location: "#" + strings.TrimPrefix(id, "#")}
+				}
+			}
+		}
+		return g, nil
+
+	case DocumentRef:
+		u.reportf(where, `runs "%s", which must be upgraded separately`, z.Location)
+		return z, nil
+	}
+	return nil, errf(`can't upgrade document type "%s"`, doc.Doctype())
+}
+
+// extensions maps cwltool extensions to the requirements which replace them.
+var extensions = map[string]string{
+	"cwltool:LoadListingRequirement":   "LoadListingRequirement",
+	"cwltool:TimeLimit":                "ToolTimeLimit",
+	"cwltool:NetworkAccess":            "NetworkAccess",
+	"cwltool:WorkReuse":                "WorkReuse",
+	"cwltool:InplaceUpdateRequirement": "InplaceUpdateRequirement",
+}
+
+// requirements reports requirements and hints which aren't modeled, and so
+// are kept as is, including cwltool extensions replaced by standard
+// requirements in v1.1.
+func (u *upgrader) requirements(where string, reqs, hints []Requirement) {
+	for _, req := range append(append([]Requirement{}, reqs...), hints...) {
+		r, ok := req.(UnknownRequirement)
+		if !ok {
+			continue
+		}
+		switch std, ok := extensions[r.Name]; {
+		case ok:
+			u.reportf(where, `replace "%s" with %s`, r.Name, std)
+		case r.Name == "":
+			u.reportf(where, "an unresolved $import or $include can't be upgraded")
+		default:
+			u.reportf(where, `"%s" isn't known, so it's kept as is and must be checked by hand`, r.Name)
+		}
+	}
+}
+
+// requireSecondaryFiles returns the structured form of v1.0 secondaryFiles,
+// which are all required. Expressions are kept as is.
+func requireSecondaryFiles(files []SecondaryFile) []SecondaryFile {
+	if files == nil {
+		return nil
+	}
+	out := make([]SecondaryFile, len(files))
+	for i, f := range files {
+		if f.Required == "" && !strings.Contains(string(f.Pattern), "$(") &&
+			!strings.Contains(string(f.Pattern), "${") {
+			f.Required = "true"
+		}
+		out[i] = f
+	}
+	return out
+}
+
+// deepListing adds a LoadListingRequirement with deep_listing, which is the
+// behavior of v1.0, unless the requirements or hints already set one.
+func deepListing(reqs, hints []Requirement) []Requirement {
+	for _, req := range append(append([]Requirement{}, reqs...), hints...) {
+		if _, ok := req.(LoadListingRequirement); ok {
+			return reqs
+		}
+	}
+	return append(append([]Requirement{}, reqs...), LoadListingRequirement{LoadListing: DeepListing})
+}
+
+// networkAccess adds a NetworkAccess requirement allowing network access,
+// which is the behavior of v1.0, unless the requirements or hints already
+// set one, including the cwltool:NetworkAccess extension.
+func networkAccess(reqs, hints []Requirement) []Requirement {
+	for _, req := range append(append([]Requirement{}, reqs...), hints...) {
+		switch z := req.(type) {
+		case NetworkAccess:
+			return reqs
+		case UnknownRequirement:
+			if z.Name == "cwltool:NetworkAccess" {
+				return reqs
+			}
+		}
+	}
+	return append(append([]Requirement{}, reqs...), NetworkAccess{NetworkAccess: "true"})
+}
+
+// hasDirectory returns true if any of the inputs or outputs is,
+// or contains, a Directory.
+func hasDirectory(inputs []CommandInput, outputs []CommandOutput) bool {
+	for _, in := range inputs {
+		if inputHasDirectory(in.Type) {
+			return true
+		}
+	}
+	for _, out := range outputs {
+		if outputHasDirectory(out.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// workflowHasDirectory returns true if any of the workflow inputs or outputs
+// is, or contains, a Directory.
+func workflowHasDirectory(inputs []WorkflowInput, outputs []WorkflowOutput) bool {
+	for _, in := range inputs {
+		if inputHasDirectory(in.Type) {
+			return true
+		}
+	}
+	for _, out := range outputs {
+		if outputHasDirectory(out.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+func inputHasDirectory(types []InputType) bool {
+	for _, t := range types {
+		switch z := t.(type) {
+		case DirectoryType:
+			return true
+		case InputArray:
+			if inputHasDirectory(z.Items) {
+				return true
+			}
+		case InputRecord:
+			for _, f := range z.Fields {
+				if inputHasDirectory(f.Type) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func outputHasDirectory(types []OutputType) bool {
+	for _, t := range types {
+		switch z := t.(type) {
+		case DirectoryType:
+			return true
+		case OutputArray:
+			if outputHasDirectory(z.Items) {
+				return true
+			}
+		case OutputRecord:
+			for _, f := range z.Fields {
+				if outputHasDirectory(f.Type) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// docWhere describes the location of a document in the report,
+// e.g. `Workflow "#main" step "rev"`.
+func docWhere(where, id, class string) string {
+	if id != "" {
+		class = fmt.Sprintf(`%s "%s"`, class, id)
+	}
+	if where == "" {
+		return class
+	}
+	return where
+}
+
+// versionIndex returns the index of a version in Versions, or -1.
+func versionIndex(v string) int {
+	for i, x := range Versions {
+		if normalizeVersion(v) == x {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,186 @@
+package cwl_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+)
+
+func TestUpgrade(t *testing.T) {
+	doc := loadDoc(t, `
+cwlVersion: v1.0
+class: Workflow
+hints:
+  cwltool:TimeLimit:
+    timelimit: 60
+inputs:
+  dir: Directory
+  bam:
+    type: File
+    secondaryFiles: [.bai, $(self.basename).idx]
+outputs:
+  out:
+    type: File
+    outputSource: list/out
+steps:
+  list:
+    in:
+      dir: dir
+    out: [out]
+    run:
+      class: CommandLineTool
+      baseCommand: ls
+      arguments:
+        - {valueFrom: "|", shellQuote: false}
+      inputs:
+        dir: Directory
+      outputs:
+        out:
+          type: File
+          secondaryFiles: .idx
+          outputBinding:
+            glob: out.txt
+  other:
+    in: []
+    out: []
+    run: other.cwl
+`)
+
+	up, report, err := cwl.Upgrade(doc, "v1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.(*cwl.Workflow).CWLVersion != "v1.0" {
+		t.Error("expected the original document to be unchanged")
+	}
+	b, err := json.Marshal(up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`"cwlVersion":"v1.2"`,
+		`{"pattern":".bai","required":true}`,
+		`"$(self.basename).idx"`,
+		`{"class":"LoadListingRequirement","loadListing":"deep_listing"}`,
+		`"shellQuote":false`,
+		`{"class":"NetworkAccess","networkAccess":true}`,
+	} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("expected %s in %s", s, b)
+		}
+	}
+
+	wf, err := cwl.LoadDocumentBytes(b, ".", cwl.NoResolve())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := wf.(*cwl.Workflow).Steps[0].Run.(*cwl.Tool)
+	sec := tool.Outputs[0].SecondaryFiles
+	if tool.CWLVersion != "v1.2" || len(sec) != 1 || sec[0] != (cwl.SecondaryFile{Pattern: ".idx", Required: "true"}) {
+		t.Errorf("unexpected upgraded tool %s", b)
+	}
+
+	expected := []string{
+		`Workflow: replace "cwltool:TimeLimit" with ToolTimeLimit`,
+		`Workflow step "other": runs "other.cwl", which must be upgraded separately`,
+	}
+	if strings.Join(report, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected report %q, got %q", expected, report)
+	}
+}
+
+func TestUpgradeErrors(t *testing.T) {
+	doc := loadDoc(t, `
+cwlVersion: v1.1
+class: CommandLineTool
+inputs: []
+outputs: []
+`)
+	if _, _, err := cwl.Upgrade(doc, "v1.0"); err == nil {
+		t.Error("expected an error downgrading a document")
+	}
+	if _, _, err := cwl.Upgrade(doc, "v2.0"); err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+	up, report, err := cwl.Upgrade(doc, "v1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up.(*cwl.Tool).CWLVersion != "v1.2" || len(report) != 0 {
+		t.Errorf("unexpected upgrade %+v, report %q", up, report)
+	}
+}
+
+func TestUpgradeNetworkAccess(t *testing.T) {
+	// The extension is kept, rather than adding a second requirement.
+	doc := loadDoc(t, `
+cwlVersion: v1.0
+class: CommandLineTool
+$namespaces:
+  cwltool: http://commonwl.org/cwltool#
+hints:
+  cwltool:NetworkAccess:
+    networkAccess: false
+inputs: []
+outputs: []
+`)
+	up, report, err := cwl.Upgrade(doc, "v1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := up.(*cwl.Tool)
+	if len(tool.Requirements) != 0 {
+		t.Errorf("unexpected requirements %#v", tool.Requirements)
+	}
+	expected := `CommandLineTool: replace "cwltool:NetworkAccess" with NetworkAccess`
+	if len(report) != 1 || report[0] != expected {
+		t.Errorf("expected report %q, got %q", expected, report)
+	}
+}
+
+func TestUpgradeWorkflowListing(t *testing.T) {
+	for src, deep := range map[string]bool{
+		`
+cwlVersion: v1.0
+class: Workflow
+inputs:
+  dirs: Directory[]
+outputs: []
+steps: []
+`: true,
+		`
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs:
+  out:
+    type: Directory
+    outputSource: dirs
+steps: []
+`: true,
+		`
+cwlVersion: v1.0
+class: Workflow
+inputs:
+  n: int
+outputs: []
+steps: []
+`: false,
+	} {
+		up, _, err := cwl.Upgrade(loadDoc(t, src), "v1.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		reqs := up.(*cwl.Workflow).Requirements
+		expected := []cwl.Requirement{cwl.LoadListingRequirement{LoadListing: cwl.DeepListing}}
+		if !deep {
+			expected = nil
+		}
+		if !reflect.DeepEqual(reqs, expected) {
+			t.Errorf("expected requirements %#v, got %#v for %s", expected, reqs, src)
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package cwl
 
 import (
+	"fmt"
 	"strings"
+
+	"github.com/go-yaml/yaml"
+	"github.com/lijiang2014/yamlast"
 )
 
 func (l *loader) SeqToValue(n node) (Value, error) {
@@ -66,3 +70,62 @@ func (l *loader) MappingToValueMap(n node) (map[string]Value, error) {
 	}
 	return vals, nil
 }
+
+// MappingToFileDir loads the items of Directory listings and File secondaryFiles.
+func (l *loader) MappingToFileDir(n node) (FileDir, error) {
+	switch strings.ToLower(findKey(n, "class")) {
+	case "file":
+		f := File{}
+		err := l.load(n, &f)
+		return f, err
+	case "directory":
+		d := Directory{}
+		err := l.load(n, &d)
+		return d, err
+	}
+	return nil, fmt.Errorf("expected a File or Directory at line %d, col %d", n.Line+1, n.Column+1)
+}
+
+// yamlValue loads a node as plain YAML data. Unlike the other Value handlers,
+// which keep scalars as strings to be coerced by their schema type, it resolves
+// the type of unquoted scalars as YAML does, e.g. 60 and true load as a number
+// and a boolean, while "60" stays a string. It's used for the fields of unknown
+// requirements, which have no schema, so they marshal as they were written.
+func (l *loader) yamlValue(n node) (Value, error) {
+	switch n.Kind {
+	case yamlast.MappingNode:
+		vals := map[string]Value{}
+		for _, kv := range itermap(n) {
+			v, err := l.yamlValue(kv.v)
+			if err != nil {
+				return nil, err
+			}
+			vals[kv.k] = v
+		}
+		return vals, nil
+	case yamlast.SequenceNode:
+		vals := []Value{}
+		for _, c := range n.Children {
+			v, err := l.yamlValue(c)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, v)
+		}
+		return vals, nil
+	case yamlast.ScalarNode:
+		if !n.Implicit {
+			return n.Value, nil
+		}
+		var v interface{}
+		if err := yaml.Unmarshal([]byte(n.Value), &v); err != nil {
+			return nil, fmt.Errorf("loading value at line %d, col %d: %s", n.Line+1, n.Column+1, err)
+		}
+		switch v.(type) {
+		case nil, bool, int, int64, uint64, float64:
+			return v, nil
+		}
+		return n.Value, nil
+	}
+	return nil, fmt.Errorf("unhandled value at line %d, col %d", n.Line+1, n.Column+1)
+}
@@ -0,0 +1,154 @@
+package cwl
+
+import (
+	"strings"
+)
+
+// Versions lists the CWL versions the loader supports, oldest first.
+var Versions = []string{"v1.0", "v1.1", "v1.2"}
+
+// normalizeVersion strips the patch and pre-release parts of a version,
+// e.g. "v1.2.0-dev5" becomes "v1.2".
+func normalizeVersion(v string) string {
+	if i := strings.Index(v, "-"); i >= 0 {
+		v = v[:i]
+	}
+	if parts := strings.Split(v, "."); len(parts) > 2 {
+		v = strings.Join(parts[:2], ".")
+	}
+	return v
+}
+
+func checkVersion(v string) error {
+	if v == "" {
+		return nil
+	}
+	for _, x := range Versions {
+		if normalizeVersion(v) == x {
+			return nil
+		}
+	}
+	return errf(`unsupported cwlVersion "%s"`, v)
+}
+
+// versionAtLeast reports whether a document of version v may use features
+// added in version min. A document without a version, e.g. a fragment
+// loaded on its own, may use every feature.
+func versionAtLeast(v, min string) bool {
+	if v == "" {
+		return true
+	}
+	v = normalizeVersion(v)
+	for _, x := range Versions {
+		switch x {
+		case v:
+			return v == min
+		case min:
+			return true
+		}
+	}
+	return false
+}
+
+// requirementVersions maps the classes of requirements added after v1.0
+// to the version which added them.
+var requirementVersions = map[string]string{
+	"LoadListingRequirement":   "v1.1",
+	"ToolTimeLimit":            "v1.1",
+	"NetworkAccess":            "v1.1",
+	"WorkReuse":                "v1.1",
+	"InplaceUpdateRequirement": "v1.1",
+}
+
+// checkFeatures returns an error if a document uses features added after its
+// cwlVersion. Hints aren't checked, since unsupported hints may be ignored.
+func checkFeatures(doc Document) error {
+	switch z := doc.(type) {
+	case *Tool:
+		if err := checkRequirements(z.CWLVersion, z.Requirements); err != nil {
+			return err
+		}
+		for _, in := range z.Inputs {
+			if err := checkSecondaryFiles(z.CWLVersion, in.ID, in.SecondaryFiles); err != nil {
+				return err
+			}
+		}
+		for _, out := range z.Outputs {
+			if err := checkSecondaryFiles(z.CWLVersion, out.ID, out.SecondaryFiles); err != nil {
+				return err
+			}
+		}
+
+	case *ExpressionTool:
+		if err := checkRequirements(z.CWLVersion, z.Requirements); err != nil {
+			return err
+		}
+		for _, in := range z.Inputs {
+			if err := checkSecondaryFiles(z.CWLVersion, in.ID, in.SecondaryFiles); err != nil {
+				return err
+			}
+		}
+		for _, out := range z.Outputs {
+			if err := checkSecondaryFiles(z.CWLVersion, out.ID, out.SecondaryFiles); err != nil {
+				return err
+			}
+		}
+
+	case *Operation:
+		if !versionAtLeast(z.CWLVersion, "v1.2") {
+			return errf(`class "Operation" requires cwlVersion v1.2, got "%s"`, z.CWLVersion)
+		}
+
+	case *Workflow:
+		v := z.CWLVersion
+		if err := checkRequirements(v, z.Requirements); err != nil {
+			return err
+		}
+		for _, in := range z.Inputs {
+			if err := checkSecondaryFiles(v, in.ID, in.SecondaryFiles); err != nil {
+				return err
+			}
+		}
+		for _, out := range z.Outputs {
+			if err := checkSecondaryFiles(v, out.ID, out.SecondaryFiles); err != nil {
+				return err
+			}
+			if out.PickValue != "" && !versionAtLeast(v, "v1.2") {
+				return errf(`output "%s": pickValue requires cwlVersion v1.2, got "%s"`, out.ID, v)
+			}
+		}
+		for _, step := range z.Steps {
+			if step.When != "" && !versionAtLeast(v, "v1.2") {
+				return errf(`step "%s": when requires cwlVersion v1.2, got "%s"`, step.ID, v)
+			}
+			for _, in := range step.In {
+				if in.PickValue != "" && !versionAtLeast(v, "v1.2") {
+					return errf(`step "%s": pickValue requires cwlVersion v1.2, got "%s"`, step.ID, v)
+				}
+			}
+			if err := checkRequirements(v, step.Requirements); err != nil {
+				return errf(`step "%s": %s`, step.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkRequirements(v string, reqs []Requirement) error {
+	for _, req := range reqs {
+		class := requirementClass(req)
+		if min, ok := requirementVersions[class]; ok && !versionAtLeast(v, min) {
+			return errf(`%s requires cwlVersion %s, got "%s"`, class, min, v)
+		}
+	}
+	return nil
+}
+
+func checkSecondaryFiles(v, id string, files []SecondaryFile) error {
+	for _, f := range files {
+		if f.Required != "" && !versionAtLeast(v, "v1.1") {
+			return errf(`"%s": secondaryFiles "required" requires cwlVersion v1.1, got "%s"`, id, v)
+		}
+	}
+	return nil
+}
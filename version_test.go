@@ -0,0 +1,212 @@
+package cwl_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lijiang2014/cwl"
+	"github.com/lijiang2014/cwl/process"
+)
+
+func TestLoadV12(t *testing.T) {
+	doc := loadDoc(t, `
+cwlVersion: v1.2
+class: Workflow
+requirements:
+  ToolTimeLimit:
+    timelimit: 60
+  NetworkAccess:
+    networkAccess: true
+  WorkReuse:
+    enableReuse: false
+  InplaceUpdateRequirement:
+    inplaceUpdate: true
+inputs:
+  bam:
+    type: File
+    secondaryFiles:
+      - .bai?
+      - pattern: ^.fai
+        required: true
+    loadListing: no_listing
+outputs:
+  out:
+    type: int
+    outputSource: [a/out, b/out]
+    pickValue: first_non_null
+steps:
+  a:
+    when: $(inputs.n > 0)
+    in:
+      n: {source: [bam], pickValue: the_only_non_null}
+    out: [out]
+    run:
+      class: Operation
+      inputs:
+        n: int
+      outputs:
+        out: int
+  b:
+    in: []
+    out: [out]
+    run:
+      class: CommandLineTool
+      inputs: []
+      outputs: []
+`)
+	wf := doc.(*cwl.Workflow)
+
+	reqs := map[string]string{}
+	for _, req := range wf.Requirements {
+		b, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v struct{ Class string }
+		json.Unmarshal(b, &v)
+		reqs[v.Class] = string(b)
+	}
+	// Literal fields marshal as booleans and numbers.
+	for class, expected := range map[string]string{
+		"ToolTimeLimit":            `{"class":"ToolTimeLimit","timelimit":60}`,
+		"NetworkAccess":            `{"class":"NetworkAccess","networkAccess":true}`,
+		"WorkReuse":                `{"class":"WorkReuse","enableReuse":false}`,
+		"InplaceUpdateRequirement": `{"class":"InplaceUpdateRequirement","inplaceUpdate":true}`,
+	} {
+		if reqs[class] != expected {
+			t.Errorf("expected %s, got %s", expected, reqs[class])
+		}
+	}
+
+	sec := wf.Inputs[0].SecondaryFiles
+	if len(sec) != 2 || sec[0] != (cwl.SecondaryFile{Pattern: ".bai", Required: "false"}) ||
+		sec[1] != (cwl.SecondaryFile{Pattern: "^.fai", Required: "true"}) {
+		t.Errorf("unexpected secondaryFiles %+v", sec)
+	}
+	if wf.Inputs[0].LoadListing != cwl.NoListing {
+		t.Errorf("unexpected loadListing %q", wf.Inputs[0].LoadListing)
+	}
+	if wf.Outputs[0].PickValue != cwl.FirstNonNull {
+		t.Errorf("unexpected output pickValue %q", wf.Outputs[0].PickValue)
+	}
+
+	a := wf.Steps[0]
+	if a.When != "$(inputs.n > 0)" || a.In[0].PickValue != cwl.TheOnlyNonNull {
+		t.Errorf("unexpected step %+v", a)
+	}
+	op, ok := a.Run.(*cwl.Operation)
+	if !ok {
+		t.Fatalf("expected an operation, got %T", a.Run)
+	}
+	// Inlined documents have the version of the workflow.
+	if op.CWLVersion != "v1.2" || wf.Steps[1].Run.(*cwl.Tool).CWLVersion != "v1.2" {
+		t.Errorf("expected inlined documents to inherit cwlVersion")
+	}
+
+	e := &process.Engine{Executor: nopExecutor{}}
+	if _, err := e.Run(op, cwl.Values{"n": 1}); err == nil {
+		t.Error("expected an error running an operation")
+	}
+}
+
+func TestVersionRules(t *testing.T) {
+	tests := []struct {
+		doc, err string
+	}{
+		{`
+cwlVersion: draft-3
+class: CommandLineTool
+inputs: []
+outputs: []
+`, "unsupported cwlVersion"},
+		{`
+cwlVersion: v1.1
+class: Operation
+inputs: []
+outputs: []
+`, "requires cwlVersion v1.2"},
+		{`
+cwlVersion: v1.0
+class: CommandLineTool
+requirements:
+  ToolTimeLimit:
+    timelimit: 60
+inputs: []
+outputs: []
+`, "ToolTimeLimit requires cwlVersion v1.1"},
+		{`
+cwlVersion: v1.1
+class: Workflow
+inputs: []
+outputs: []
+steps:
+  a:
+    when: $(true)
+    in: []
+    out: []
+    run:
+      class: CommandLineTool
+      inputs: []
+      outputs: []
+`, "when requires cwlVersion v1.2"},
+		{`
+cwlVersion: v1.0
+class: ExpressionTool
+requirements:
+  InlineJavascriptRequirement: {}
+inputs:
+  bam:
+    type: File
+    secondaryFiles:
+      - pattern: .bai
+        required: true
+outputs: []
+expression: "$({})"
+`, `"bam": secondaryFiles "required" requires cwlVersion v1.1`},
+		{`
+cwlVersion: v1.0
+class: Workflow
+inputs: []
+outputs:
+  out:
+    type: File
+    outputSource: a/out
+    secondaryFiles:
+      - pattern: .bai
+        required: false
+steps: []
+`, `"out": secondaryFiles "required" requires cwlVersion v1.1`},
+	}
+	for _, test := range tests {
+		_, err := cwl.LoadDocumentBytes([]byte(test.doc), ".", cwl.NoResolve())
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("expected an error containing %q, got %v", test.err, err)
+		}
+	}
+
+	// v1.0 requirements may be given as hints, and "?" isn't special.
+	tool := loadDoc(t, `
+cwlVersion: v1.0
+class: CommandLineTool
+hints:
+  ToolTimeLimit:
+    timelimit: 60
+inputs:
+  bam:
+    type: File
+    secondaryFiles: .bai?
+outputs: []
+`).(*cwl.Tool)
+	sec := tool.Inputs[0].SecondaryFiles
+	if len(sec) != 1 || sec[0] != (cwl.SecondaryFile{Pattern: ".bai?"}) {
+		t.Errorf("unexpected secondaryFiles %+v", sec)
+	}
+	b, err := json.Marshal(tool.Inputs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"secondaryFiles":[".bai?"]`) {
+		t.Errorf("expected secondaryFiles to marshal as patterns, got %s", b)
+	}
+}
@@ -24,8 +24,10 @@ type WorkflowInput struct {
 
 	Type           []InputType         `json:"type,omitempty"`
 
-	SecondaryFiles []Expression        `json:"secondaryFiles,omitempty"`
+	SecondaryFiles []SecondaryFile     `json:"secondaryFiles,omitempty"`
 	Format         []Expression        `json:"format,omitempty"`
+	LoadContents   bool                `json:"loadContents,omitempty"`
+	LoadListing    LoadListing         `json:"loadListing,omitempty"`
 
 	InputBinding   *CommandLineBinding `json:"inputBinding,omitempty"`
 }
@@ -37,12 +39,13 @@ type WorkflowOutput struct {
 	Streamable bool            `json:"streamable,omitempty"`
 	LinkMerge  LinkMergeMethod `json:"linkMerge,omitempty"`
 
-	Type           []OutputType `json:"type,omitempty"`
-	SecondaryFiles []Expression `json:"secondaryFiles,omitempty"`
-	Format         []Expression `json:"format,omitempty"`
+	Type           []OutputType    `json:"type,omitempty"`
+	SecondaryFiles []SecondaryFile `json:"secondaryFiles,omitempty"`
+	Format         []Expression    `json:"format,omitempty"`
 
 	OutputBinding *CommandOutputBinding `json:"outputBinding,omitempty"`
 	OutputSource  []string              `json:"outputSource,omitempty"`
+	PickValue     PickValueMethod       `json:"pickValue,omitempty"`
 }
 
 type Step struct {
@@ -60,12 +63,17 @@ type Step struct {
 
 	Scatter       []string      `json:"scatter,omitempty"`
 	ScatterMethod ScatterMethod `json:"scatterMethod,omitempty"`
+
+	// When is a condition for running the step, added in v1.2.
+	// A step whose condition is false is skipped, and its outputs are null.
+	When Expression `json:"when,omitempty"`
 }
 
 type StepInput struct {
 	ID        string          `json:"id,omitempty"`
 	Source    []string        `json:"source,omitempty"`
 	LinkMerge LinkMergeMethod `json:"linkMerge,omitempty"`
+	PickValue PickValueMethod `json:"pickValue,omitempty"`
 	Default   Value           `json:"default,omitempty"`
 	ValueFrom Expression      `json:"valueFrom,omitempty"`
 }